@@ -0,0 +1,230 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// XPChange is a single entry in the XP audit log.
+type XPChange struct {
+	PlayerID  uuid.UUID `json:"player_id"`
+	Delta     int64     `json:"delta"`
+	Total     uint64    `json:"total"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// InitAuditTable creates the XP audit table if it doesn't exist.
+// Set the Store's AuditTable to the same name to start recording changes.
+func InitAuditTable(db *sql.DB, auditTableName string) error {
+	if db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	table, err := quoteTableName(auditTableName)
+	if err != nil {
+		return err
+	}
+
+	index, err := indexName(auditTableName, "player_idx")
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id BIGSERIAL PRIMARY KEY,
+			player_id UUID NOT NULL,
+			delta INT8 NOT NULL,
+			total INT8 NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`, table)
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create audit table: %w", err)
+	}
+
+	indexQuery := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s
+		ON %s (player_id, created_at DESC)
+	`, index, table)
+
+	_, err = db.Exec(indexQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create audit table index: %w", err)
+	}
+	return nil
+}
+
+// GetXPHistory returns the most recent XP changes recorded for a player,
+// newest first.
+func GetXPHistory(db *sql.DB, auditTableName string, id uuid.UUID, limit int) ([]XPChange, error) {
+	s := NewStore[any](db, "")
+	s.AuditTable = auditTableName
+	return s.GetXPHistory(id, limit)
+}
+
+// GetXPHistory returns the most recent XP changes recorded for a player in
+// the Store's AuditTable, newest first.
+func (s *Store[T]) GetXPHistory(id uuid.UUID, limit int) ([]XPChange, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if s.AuditTable == "" {
+		return nil, fmt.Errorf("%w: auditing is not enabled", ErrInvalidData)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if err := checkLimit("history limit", limit); err != nil {
+		return nil, err
+	}
+
+	table, err := s.auditTable()
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+		SELECT player_id, delta, total, created_at
+		FROM %s
+		WHERE player_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`, table)
+
+	var changes []XPChange
+	err = s.do("xp_history", func(ctx context.Context) error {
+		rows, err := s.reader().QueryContext(ctx, query, id, limit)
+		if err != nil {
+			return fmt.Errorf("failed to query xp history: %w", err)
+		}
+		defer rows.Close()
+
+		changes = nil
+		for rows.Next() {
+			var change XPChange
+			err := rows.Scan(
+				&change.PlayerID,
+				&change.Delta,
+				&change.Total,
+				&change.CreatedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan xp history row: %w", err)
+			}
+			changes = append(changes, change)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through xp history rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return changes, nil
+}
+
+// execWithAudit runs a player update and, when auditing is enabled and XP
+// changed, records the change in the audit table within the same transaction.
+// The player's new XP and level are also sent on the Store's NotifyChannel.
+// ErrPlayerNotFound is returned, and nothing recorded, if no row was updated.
+// When dest is non-nil, the query must end in a RETURNING clause whose
+// columns are scanned into dest.
+// When tx is nil, a transaction is started only if one is needed.
+func (s *Store[T]) execWithAudit(ctx context.Context, tx Querier, id uuid.UUID, delta int64, total uint64, level uint32, dest []any, query string, args ...any) error {
+	if tx == nil {
+		if (s.AuditTable == "" || delta == 0) && s.NotifyChannel == "" {
+			return execUpdate(ctx, s.db, dest, query, args...)
+		}
+
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := s.execWithAudit(ctx, tx, id, delta, total, level, dest, query, args...); err != nil {
+			return err
+		}
+		return tx.Commit()
+	}
+
+	if err := execUpdate(ctx, tx, dest, query, args...); err != nil {
+		return err
+	}
+
+	if err := s.recordXPChange(ctx, tx, id, delta, total); err != nil {
+		return err
+	}
+
+	return s.notifyChange(ctx, tx, PlayerChange{ID: id, XP: total, Level: level})
+}
+
+// execUpdate runs a single-row update on q, scanning its RETURNING columns
+// into dest when dest is non-nil. ErrPlayerNotFound is returned if no row was
+// updated.
+func execUpdate(ctx context.Context, q Querier, dest []any, query string, args ...any) error {
+	if dest == nil {
+		result, err := q.ExecContext(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		return requireRowAffected(result)
+	}
+
+	err := q.QueryRowContext(ctx, query, args...).Scan(dest...)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrPlayerNotFound
+	}
+	return err
+}
+
+// recordXPChange writes an entry to the audit table when auditing is enabled.
+// Callers run it in the transaction that changed the player's XP.
+func (s *Store[T]) recordXPChange(ctx context.Context, q Querier, id uuid.UUID, delta int64, total uint64) error {
+	if s.AuditTable == "" || delta == 0 {
+		return nil
+	}
+
+	table, err := s.auditTable()
+	if err != nil {
+		return err
+	}
+
+	audit := fmt.Sprintf(`
+		INSERT INTO %s (player_id, delta, total)
+		VALUES ($1, $2, $3)
+		`, table)
+
+	if _, err := q.ExecContext(ctx, audit, id, delta, total); err != nil {
+		return fmt.Errorf("failed to record xp change: %w", err)
+	}
+	return nil
+}
+
+// indexName derives a quoted index name from a possibly schema-qualified
+// table name, since Postgres creates indexes in the table's own schema.
+func indexName(tableName, suffix string) (string, error) {
+	parts, err := splitTableName(tableName)
+	if err != nil {
+		return "", err
+	}
+	return quoteIdentifier(parts[len(parts)-1] + "_" + suffix), nil
+}
+
+// auditTable returns the Store's AuditTable quoted for use in SQL.
+func (s *Store[T]) auditTable() (string, error) {
+	return quoteTableName(s.AuditTable)
+}
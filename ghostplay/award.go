@@ -0,0 +1,169 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/google/uuid"
+)
+
+// AwardXPAndSetFlags awards xp to a player and sets the given flags in a
+// single UPDATE, e.g., to complete a tutorial without risking a partial write.
+func AwardXPAndSetFlags(db *sql.DB, dbTableName string, id uuid.UUID, xp uint64, flags map[string]bool) (SaveResult, error) {
+	return NewStore[any](db, dbTableName).AwardXPAndSetFlags(id, xp, flags)
+}
+
+// AwardXPAndSetFlags awards xp to a player and sets the given flags, leaving
+// their other flags and extra data untouched. The XP, level, and flags are
+// written by a single UPDATE, so either all of the changes apply or none do;
+// a transaction is started only to audit or notify the award. When AutoLevel
+// is enabled the level is derived from the new XP on the Store's LevelCurve,
+// never demoted, and computed up to level 100,000; players awarded XP
+// concurrently beyond the XP read when the curve is prepared are capped one
+// level above it.
+// Like Save, the award is audited and notified, and AfterSave is called.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) AwardXPAndSetFlags(id uuid.UUID, xp uint64, flags map[string]bool) (SaveResult, error) {
+	if s.db == nil {
+		return SaveResult{}, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return SaveResult{}, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if xp > math.MaxInt64 {
+		return SaveResult{}, fmt.Errorf("%w: xp award is too large", ErrInvalidData)
+	}
+
+	for key := range flags {
+		if key == "" {
+			return SaveResult{}, fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+		}
+	}
+
+	if err := s.checkFlags(flags); err != nil {
+		return SaveResult{}, err
+	}
+
+	// A nil map would marshal as null, which jsonb || doesn't merge
+	if flags == nil {
+		flags = make(map[string]bool)
+	}
+
+	flagsJSON, err := marshalFlags(s.codec(), flags)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	// The last update time is filled in per attempt and the thresholds, when
+	// needed, follow the other arguments
+	args := []any{id, int64(xp), flagsJSON, nil}
+	total := "{xp} + $2"
+	if s.GateLevel > 0 {
+		args = append(args, int64(min(s.gateXP(), math.MaxInt64)), s.GateLevel)
+		total = "LEAST({xp} + $2, GREATEST($5::int8, {xp}))"
+	}
+
+	level := "{level}"
+	if s.AutoLevel {
+		level = "GREATEST({level}, 1 + width_bucket(" + total + ", $" + strconv.Itoa(len(args)+1) + "::int8[]))"
+	}
+
+	if s.GateLevel > 0 {
+		level = "LEAST(" + level + ", GREATEST($6::int8, {level}))"
+	}
+
+	// The subquery names the previous values so RETURNING can report them
+	query := s.expand(`
+		UPDATE {table}
+		SET {xp} = ` + total + `,
+			{level} = ` + level + `,
+			{flags} = COALESCE({flags}, '{}'::jsonb) || $3::jsonb,
+			{last_updated} = $4,
+			{updated_by} = NULL
+		FROM (
+			SELECT {xp}, {level}
+			FROM {table}
+			WHERE {id} = $1 AND {live}
+			FOR UPDATE
+		) AS previous(previous_xp, previous_level)
+		WHERE {id} = $1 AND {live}
+		RETURNING previous_xp, previous_level, {xp}, {level}
+		`)
+
+	current := s.expand(`
+		SELECT {xp}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	var result SaveResult
+	err = s.do("award_xp_and_set_flags", func(ctx context.Context) error {
+		args := append([]any(nil), args...)
+		args[3] = s.now()
+		if s.AutoLevel {
+			var stored uint64
+			err := s.db.QueryRowContext(ctx, current, s.seasonArgs(id)...).Scan(&stored)
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrPlayerNotFound
+			}
+
+			if err != nil {
+				return fmt.Errorf("failed to query player xp: %w", err)
+			}
+
+			args = append(args, s.curve().thresholds(stored+min(xp, math.MaxUint64-stored)))
+		}
+
+		// A transaction is only needed to audit or notify alongside the update
+		var q Querier = s.db
+		var tx *sql.Tx
+		if (s.AuditTable != "" && xp > 0) || s.NotifyChannel != "" {
+			var err error
+			tx, err = s.beginTx(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback()
+			q = tx
+		}
+
+		err := q.QueryRowContext(ctx, query, s.seasonArgs(args...)...).Scan(&result.PreviousXP, &result.PreviousLevel, &result.XP, &result.Level)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlayerNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to award xp and set flags: %w", err)
+		}
+
+		if tx == nil {
+			return nil
+		}
+
+		if err := s.recordXPChange(ctx, tx, id, int64(result.XP-result.PreviousXP), result.XP); err != nil {
+			return err
+		}
+
+		if err := s.notifyChange(ctx, tx, PlayerChange{ID: id, XP: result.XP, Level: result.Level}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit award: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	result.DiscardedXP = xp - (result.XP - result.PreviousXP)
+	s.afterSave(id, result)
+	return result, nil
+}
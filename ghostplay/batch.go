@@ -0,0 +1,274 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PlayerSeed is the identity of a player to create with InitPlayers.
+type PlayerSeed struct {
+	ID       uuid.UUID
+	Username string
+	Phrase   string
+}
+
+// insertBatchSize caps the rows per INSERT statement to stay well below the
+// Postgres limit on bind parameters.
+const insertBatchSize = 1000
+
+// InitPlayers creates many players in a single transaction.
+// If any phrase is already taken the whole batch is rolled back and
+// ErrPhraseTaken is returned naming the offending phrase.
+func InitPlayers(db *sql.DB, dbTableName string, players []PlayerSeed) error {
+	return NewStore[any](db, dbTableName).InitPlayers(players)
+}
+
+// InitPlayers creates many players in a single transaction, starting from the
+// Store's StartingLevel and StartingXP.
+// If any phrase is already taken the whole batch is rolled back and
+// ErrPhraseTaken is returned naming the offending phrase.
+func (s *Store[T]) InitPlayers(players []PlayerSeed) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if len(players) == 0 {
+		return nil
+	}
+
+	phrases := make(map[string]bool, len(players))
+	for _, player := range players {
+		if player.ID == uuid.Nil {
+			return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+		}
+
+		if player.Username == "" || player.Phrase == "" {
+			return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+		}
+
+		if err := s.checkLengths(player.Username, player.Phrase); err != nil {
+			return err
+		}
+
+		phrase := s.normalizePhrase(player.Phrase)
+		if phrases[phrase] {
+			return fmt.Errorf("%w: %q appears more than once in the batch", ErrPhraseTaken, player.Phrase)
+		}
+		phrases[phrase] = true
+	}
+
+	return s.do("init_players", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		for start := 0; start < len(players); start += insertBatchSize {
+			end := min(start+insertBatchSize, len(players))
+			if err := s.insertPlayers(ctx, tx, players[start:end]); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit players: %w", err)
+		}
+		return nil
+	})
+}
+
+// insertPlayers inserts players with one multi-row INSERT.
+func (s *Store[T]) insertPlayers(ctx context.Context, tx *sql.Tx, players []PlayerSeed) error {
+	extraData, err := s.defaultExtraData()
+	if err != nil {
+		return err
+	}
+
+	// $1 to $4 hold the starting level, XP, extra data, and season shared by
+	// every row
+	args := []any{s.StartingLevel, s.StartingXP, extraData, s.Season}
+	values := make([]string, len(players))
+	for i, player := range players {
+		phrase, err := s.storedPhrase(player.Phrase)
+		if err != nil {
+			return err
+		}
+
+		n := len(args)
+		values[i] = fmt.Sprintf("($%d, $%d, $%d, $1, $2, $3, $4)", n+1, n+2, n+3)
+		args = append(args, player.ID, player.Username, phrase)
+	}
+
+	query := s.expand(`
+		INSERT INTO {table} ({id}, {user_name}, {phrase}, {level}, {xp}, {extra_data}, {season_id})
+		VALUES `) + strings.Join(values, ", ")
+
+	_, err = tx.ExecContext(ctx, query, args...)
+	if err == nil {
+		return nil
+	}
+
+	if hasSQLState(err, sqlStateUniqueViolation) {
+		if phrase, ok := s.takenPhrase(ctx, players); ok {
+			return fmt.Errorf("%w: %q", ErrPhraseTaken, phrase)
+		}
+	}
+
+	return fmt.Errorf("failed to create players: %w", err)
+}
+
+// takenPhrase looks up one of the players' phrases that is already stored.
+// It runs outside the failed transaction, which can no longer be queried.
+func (s *Store[T]) takenPhrase(ctx context.Context, players []PlayerSeed) (string, bool) {
+	// Map stored phrases back so the error names what the caller passed in
+	phrases := make([]string, 0, len(players))
+	original := make(map[string]string, len(players))
+	for _, player := range players {
+		stored, err := s.storedPhrase(player.Phrase)
+		if err != nil {
+			return "", false
+		}
+		phrases = append(phrases, stored)
+		original[stored] = player.Phrase
+	}
+
+	query := s.expand(`
+		SELECT {phrase}
+		FROM {table}
+		WHERE {phrase} = ANY($1::text[]) AND {season}
+		LIMIT 1
+		`)
+
+	var phrase string
+	err := s.db.QueryRowContext(ctx, query, s.seasonArgs(textArray(phrases))...).Scan(&phrase)
+	return original[phrase], err == nil
+}
+
+// GetUserStatesOrdered fetches the players with the given UUIDs in one query.
+// The returned slice is aligned with ids; players that were not found are nil.
+func GetUserStatesOrdered[T any](db *sql.DB, dbTableName string, ids []uuid.UUID) ([]*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetUserStatesOrdered(ids)
+}
+
+// GetUserStatesOrdered fetches the players with the given UUIDs in one query.
+// The returned slice is aligned with ids; players that were not found are nil.
+func (s *Store[T]) GetUserStatesOrdered(ids []uuid.UUID) ([]*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	states := make([]*PlayerState[T], len(ids))
+	if len(ids) == 0 {
+		return states, nil
+	}
+
+	for _, id := range ids {
+		if id == uuid.Nil {
+			return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+		}
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {id} = ANY($1::uuid[]) AND {live}
+		`)
+
+	var players []*PlayerState[T]
+	err := s.do("get_by_ids", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(uuidArray(ids))...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[uuid.UUID]*PlayerState[T], len(players))
+	for _, state := range players {
+		found[state.ID] = state
+	}
+
+	// Reorder to match the input, leaving gaps for missing players
+	for i, id := range ids {
+		states[i] = found[id]
+	}
+
+	return states, nil
+}
+
+// queryPlayers runs a query selecting {player_columns} and scans every row.
+func (s *Store[T]) queryPlayers(ctx context.Context, q Querier, query string, args ...any) ([]*PlayerState[T], error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query players: %w", err)
+	}
+	defer rows.Close()
+
+	var players []*PlayerState[T]
+	for rows.Next() {
+		state, err := s.scanPlayer(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan player row: %w", err)
+		}
+		players = append(players, state)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through player rows: %w", err)
+	}
+
+	return players, nil
+}
+
+// queryIDs runs a query returning player ids, such as an UPDATE ... RETURNING
+// {id}, and scans every row.
+func queryIDs(ctx context.Context, q Querier, query string, args ...any) ([]uuid.UUID, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan player id: %w", err)
+		}
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through player ids: %w", err)
+	}
+
+	return ids, nil
+}
+
+// uuidArray formats ids as a Postgres array literal so it can be bound as a
+// single uuid[] parameter without a driver-specific array type.
+func uuidArray(ids []uuid.UUID) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = id.String()
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// textArray formats values as a Postgres array literal, quoting each element,
+// so it can be bound as a single text[] parameter.
+func textArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		v = strings.ReplaceAll(v, `\`, `\\`)
+		v = strings.ReplaceAll(v, `"`, `\"`)
+		quoted[i] = `"` + v + `"`
+	}
+	return "{" + strings.Join(quoted, ",") + "}"
+}
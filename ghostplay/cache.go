@@ -0,0 +1,82 @@
+package ghostplay
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CachedLeaderboard serves leaderboard results from memory and refreshes them
+// once they are older than its TTL. Concurrent callers that find the cache
+// stale share a single refresh query.
+type CachedLeaderboard struct {
+	load  func(limit int) ([]Leader, error)
+	limit int
+	ttl   time.Duration
+
+	mu        sync.Mutex
+	leaders   []Leader
+	fetchedAt time.Time
+	valid     bool
+}
+
+// NewCachedLeaderboard returns a cache of the top limit players by XP that
+// is refreshed after ttl has elapsed.
+func NewCachedLeaderboard(db *sql.DB, dbTableName string, limit int, ttl time.Duration) (*CachedLeaderboard, error) {
+	return NewStore[any](db, dbTableName).CachedLeaderboard(limit, ttl)
+}
+
+// CachedLeaderboard returns a cache of the top limit players by XP that is
+// refreshed from the Store after ttl has elapsed.
+func (s *Store[T]) CachedLeaderboard(limit int, ttl time.Duration) (*CachedLeaderboard, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	if ttl <= 0 {
+		return nil, fmt.Errorf("%w: cache TTL must be greater than zero", ErrInvalidData)
+	}
+
+	return &CachedLeaderboard{
+		load:  s.GetLeaderboard,
+		limit: limit,
+		ttl:   ttl,
+	}, nil
+}
+
+// Get returns the cached leaderboard, querying the database first if the
+// cache is empty, expired, or invalidated.
+// The returned slice is a copy and may be modified by the caller.
+func (c *CachedLeaderboard) Get() ([]Leader, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.valid || time.Since(c.fetchedAt) >= c.ttl {
+		leaders, err := c.load(c.limit)
+		if err != nil {
+			return nil, err
+		}
+
+		c.leaders = leaders
+		c.fetchedAt = time.Now()
+		c.valid = true
+	}
+
+	leaders := make([]Leader, len(c.leaders))
+	copy(leaders, c.leaders)
+	return leaders, nil
+}
+
+// Invalidate discards the cached leaderboard so the next Get queries the
+// database, e.g., after a large XP event.
+func (c *CachedLeaderboard) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.valid = false
+}
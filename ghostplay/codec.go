@@ -0,0 +1,32 @@
+package ghostplay
+
+import "encoding/json"
+
+// Codec encodes and decodes the JSON stored in the flags and extra data
+// columns, e.g., to swap in a faster JSON library. Implementations must be
+// safe for concurrent use and produce JSON Postgres accepts as jsonb.
+type Codec interface {
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+// JSONCodec is the Codec backed by encoding/json, used when a Store has none.
+type JSONCodec struct{}
+
+// Marshal encodes v with json.Marshal.
+func (JSONCodec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data into v with json.Unmarshal.
+func (JSONCodec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// codec returns the Store's Codec, falling back to JSONCodec.
+func (s *Store[T]) codec() Codec {
+	if s.Codec != nil {
+		return s.Codec
+	}
+	return JSONCodec{}
+}
@@ -0,0 +1,177 @@
+package ghostplay
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Columns maps each piece of player state to a column name in the player
+// state table. It lets a Store target an existing table whose columns are
+// named differently from the defaults. Names follow the rules of table names:
+// unquoted names must be plain identifiers and are case-insensitive; wrap a
+// name in double quotes to keep its case. Every operation of a Store with an
+// invalid name fails with ErrInvalidData.
+type Columns struct {
+	ID          string
+	Phrase      string
+	UserName    string
+	Level       string
+	XP          string
+	LastUpdated string
+	Flags       string
+	ExtraData   string
+	DeletedAt   string
+	UpdatedBy   string
+	Rank        string
+	SeasonID    string
+	LastDecayed string
+}
+
+// DefaultColumns returns the column names used by InitPlayerStateTable.
+func DefaultColumns() Columns {
+	return Columns{
+		ID:          "id",
+		Phrase:      "phrase",
+		UserName:    "user_name",
+		Level:       "level",
+		XP:          "xp",
+		LastUpdated: "last_updated",
+		Flags:       "flags",
+		ExtraData:   "extra_data",
+		DeletedAt:   "deleted_at",
+		UpdatedBy:   "updated_by",
+		Rank:        "rank",
+		SeasonID:    "season_id",
+		LastDecayed: "last_decayed",
+	}
+}
+
+// expand fills in the placeholders of a query template.
+// Column placeholders use the default column names (e.g., {xp}) and are
+// replaced with the Store's mapped names. {table} is the player state table,
+// {player_columns} the column list read by scanPlayer, {season} the
+// predicate matching the Store's Season, {season_value} the Season itself,
+// and {live} the predicate matching the Store's Season and hiding
+// soft-deleted players.
+//
+// The Season is bound as the parameter numbered after the highest one in the
+// template, so a query using any of the season placeholders must be run with
+// seasonArgs, and nothing numbered after it may be appended.
+func (s *Store[T]) expand(query string) string {
+	c := s.quotedColumns()
+
+	seasonValue := "$" + strconv.Itoa(highestParam(query)+1) + "::text"
+	season := c.SeasonID + " = " + seasonValue
+
+	live := c.DeletedAt + " IS NULL AND " + season
+	if s.IncludeDeleted {
+		live = season
+	}
+
+	playerColumns := strings.Join([]string{
+		c.ID,
+		c.UserName,
+		c.Phrase,
+		c.Level,
+		c.XP,
+		c.LastUpdated,
+		c.Flags,
+		c.ExtraData,
+		c.DeletedAt,
+		c.UpdatedBy,
+		c.Rank,
+	}, ", ")
+
+	return strings.NewReplacer(
+		"{table}", s.table,
+		"{player_columns}", playerColumns,
+		"{live}", live,
+		"{season}", season,
+		"{season_value}", seasonValue,
+		"{id}", c.ID,
+		"{phrase}", c.Phrase,
+		"{user_name}", c.UserName,
+		"{level}", c.Level,
+		"{xp}", c.XP,
+		"{last_updated}", c.LastUpdated,
+		"{flags}", c.Flags,
+		"{extra_data}", c.ExtraData,
+		"{deleted_at}", c.DeletedAt,
+		"{updated_by}", c.UpdatedBy,
+		"{rank}", c.Rank,
+		"{season_id}", c.SeasonID,
+		"{last_decayed}", c.LastDecayed,
+	).Replace(query)
+}
+
+// fields returns pointers to every column name in c.
+func (c *Columns) fields() []*string {
+	return []*string{
+		&c.ID,
+		&c.Phrase,
+		&c.UserName,
+		&c.Level,
+		&c.XP,
+		&c.LastUpdated,
+		&c.Flags,
+		&c.ExtraData,
+		&c.DeletedAt,
+		&c.UpdatedBy,
+		&c.Rank,
+		&c.SeasonID,
+		&c.LastDecayed,
+	}
+}
+
+// quoted returns c with every column name validated and quoted for use in SQL.
+func (c Columns) quoted() (Columns, error) {
+	for _, name := range c.fields() {
+		column, err := columnName(*name)
+		if err != nil {
+			return Columns{}, err
+		}
+		*name = quoteIdentifier(column)
+	}
+	return c, nil
+}
+
+// quotedColumns returns the Store's Columns quoted for use in SQL, quoting
+// them the first time it is called. Invalid names are left as they are,
+// since checkColumns fails every operation before a query using them runs.
+func (s *Store[T]) quotedColumns() Columns {
+	s.columnsOnce.Do(func() {
+		s.columns, s.columnsErr = s.Columns.quoted()
+	})
+
+	if s.columnsErr != nil {
+		return s.Columns
+	}
+	return s.columns
+}
+
+// checkColumns reports whether the Store's Columns are all valid names.
+func (s *Store[T]) checkColumns() error {
+	s.quotedColumns()
+	return s.columnsErr
+}
+
+// paramPattern matches a query's positional parameters.
+var paramPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9_$"])\$([0-9]+)`)
+
+// highestParam returns the number of the highest positional parameter in
+// query, or zero if it has none.
+func highestParam(query string) int {
+	highest := 0
+	for _, m := range paramPattern.FindAllStringSubmatch(query, -1) {
+		n, _ := strconv.Atoi(m[1])
+		highest = max(highest, n)
+	}
+	return highest
+}
+
+// seasonArgs appends the Store's Season to args, for queries whose templates
+// use {season}, {season_value}, or {live}.
+func (s *Store[T]) seasonArgs(args ...any) []any {
+	return append(args, s.Season)
+}
@@ -0,0 +1,126 @@
+package ghostplay
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestContextError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	expired, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Minute))
+	defer cancel()
+
+	driverErr := errors.New("pq: canceling statement due to user request")
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{"nil error", canceled, nil, nil},
+		{"live context", context.Background(), driverErr, driverErr},
+		{"canceled", canceled, driverErr, context.Canceled},
+		{"expired", expired, driverErr, context.DeadlineExceeded},
+		{"already wrapped", canceled, fmt.Errorf("query: %w", context.Canceled), context.Canceled},
+	}
+
+	for _, tt := range tests {
+		err := contextError(tt.ctx, tt.err)
+		if tt.want == nil {
+			if err != nil {
+				t.Errorf("%s: contextError() = %v, want nil", tt.name, err)
+			}
+			continue
+		}
+
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%s: contextError() = %v, want it to match %v", tt.name, err, tt.want)
+		}
+
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: contextError() = %v, want it to keep %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	db := fakeDB(t, nil)
+	s := NewStore[any](db, "players")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	player, err := json.Marshal(NewPlayerState[any]("player", "phrase"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dump := string(player) + "\n"
+
+	calls := map[string]func() error{
+		"Store.DumpAll": func() error {
+			return s.DumpAll(ctx, &bytes.Buffer{})
+		},
+		"DumpAll": func() error {
+			return DumpAll[any](ctx, db, "players", &bytes.Buffer{})
+		},
+		"Store.LoadAll": func() error {
+			_, err := s.LoadAll(ctx, strings.NewReader(dump))
+			return err
+		},
+		"LoadAll": func() error {
+			_, err := LoadAll[any](ctx, db, "players", strings.NewReader(dump))
+			return err
+		},
+		"Store.IteratePlayers": func() error {
+			_, _, err := s.IteratePlayers(ctx, uuid.Nil, 10)
+			return err
+		},
+		"IteratePlayers": func() error {
+			_, _, err := IteratePlayers[any](ctx, db, "players", uuid.Nil, 10)
+			return err
+		},
+		"Store.QueryLeaderboardRows": func() error {
+			_, err := s.QueryLeaderboardRows(ctx, 10)
+			return err
+		},
+		"QueryLeaderboardRows": func() error {
+			_, err := QueryLeaderboardRows(ctx, db, "players", 10)
+			return err
+		},
+		"Store.SaveContext": func() error {
+			_, err := s.SaveContext(ctx, db, NewPlayerState[any]("player", "phrase"), 10)
+			return err
+		},
+		"PlayerState.SaveContext": func() error {
+			_, err := NewPlayerState[any]("player", "phrase").SaveContext(ctx, db, "players", 10)
+			return err
+		},
+		"Listen": func() error {
+			listener := pq.NewListener("host=127.0.0.1 port=1 sslmode=disable", time.Second, time.Second, nil)
+			defer listener.Close()
+
+			changes, errs := Listen(ctx, listener, "player_changes")
+			for range changes {
+			}
+			return <-errs
+		},
+	}
+
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("%s = %v, want context.Canceled", name, err)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+)
+
+// DecayInactivePlayers removes decayPerDay XP for each whole day of inactivity
+// beyond inactiveSince from every player whose last update is older than it
+// and returns the number of players affected.
+func DecayInactivePlayers(db *sql.DB, dbTableName string, inactiveSince time.Time, decayPerDay uint64) (int64, error) {
+	return NewStore[any](db, dbTableName).DecayInactivePlayers(inactiveSince, decayPerDay)
+}
+
+// DecayInactivePlayers removes decayPerDay XP for each whole day a player has
+// been inactive beyond the cutoff inactiveSince, from every player whose last
+// update is older than it, and returns the number of players affected. The
+// time before the cutoff is a grace period: with a cutoff of a week ago, a
+// player last active eight and a half days ago loses one day's decay, and one
+// active six days ago loses none.
+// Days are counted up to the cutoff from the later of the player's last update
+// and the point the last decay applied to them reached, which is recorded in
+// the last_decayed column, so with a cutoff moving along with the clock a
+// player loses the same XP however often it runs: a missed run is made up by
+// the next one and a repeated run within the day decays nothing. last_updated
+// is left untouched so players keep decaying until they are active again.
+//
+// XP is clamped at zero and, when AutoLevel is enabled, levels are demoted to
+// match the remaining XP on the Store's LevelCurve, never below StartingLevel
+// and never promoted. Players left with the XP for level 100,000, the highest
+// computed in the database, keep their level.
+// The whole decay runs as a single UPDATE.
+func (s *Store[T]) DecayInactivePlayers(inactiveSince time.Time, decayPerDay uint64) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if decayPerDay == 0 {
+		return 0, fmt.Errorf("%w: decay per day must be greater than zero", ErrInvalidData)
+	}
+
+	if decayPerDay > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: decay per day is too large", ErrInvalidData)
+	}
+
+	// Whole days from the player's last update or decay until the cutoff
+	since := `GREATEST({last_updated}, COALESCE({last_decayed}, {last_updated}))`
+	days := `floor(extract(epoch FROM $1::timestamptz - ` + since + `) / 86400)`
+	remaining := `({xp} - LEAST({xp}::numeric, ` + days + ` * $2))::int8`
+
+	// Only whole days are recorded as decayed, so partial ones carry over
+	set := `{xp} = ` + remaining + `,
+			{last_decayed} = ` + since + ` + ` + days + ` * interval '1 day'`
+	where := `WHERE {last_updated} < $1 AND {xp} > 0 AND {live} AND ` + days + ` >= 1`
+
+	query := s.expand(`
+		UPDATE {table}
+		SET ` + set + `
+		` + where)
+
+	leveled := s.expand(`
+		UPDATE {table}
+		SET ` + set + `,
+			{level} = CASE
+				WHEN 1 + width_bucket(` + remaining + `, $4::int8[]) >= ` + maxCurveLevel + `
+				THEN {level}
+				ELSE LEAST({level}, GREATEST($3, 1 + width_bucket(` + remaining + `, $4::int8[])))
+			END
+		` + where)
+
+	maxQuery := s.expand(`
+		SELECT COALESCE(MAX({xp}), 0)
+		FROM {table}
+		WHERE {last_updated} < $1 AND {live}
+		`)
+
+	var affected int64
+	err := s.do("decay", func(ctx context.Context) error {
+		var result sql.Result
+		var err error
+		if s.AutoLevel {
+			var maxXP uint64
+			if err := s.db.QueryRowContext(ctx, maxQuery, s.seasonArgs(inactiveSince)...).Scan(&maxXP); err != nil {
+				return fmt.Errorf("failed to query max xp: %w", err)
+			}
+
+			thresholds := s.curve().thresholds(maxXP)
+			result, err = s.db.ExecContext(ctx, leveled, s.seasonArgs(inactiveSince, int64(decayPerDay), s.StartingLevel, thresholds)...)
+		} else {
+			result, err = s.db.ExecContext(ctx, query, s.seasonArgs(inactiveSince, int64(decayPerDay))...)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to decay inactive players: %w", err)
+		}
+
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.afterBulkSave(affected)
+	return affected, nil
+}
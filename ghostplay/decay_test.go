@@ -0,0 +1,93 @@
+package ghostplay
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// activePlayer creates a player holding xp whose last update was at.
+func activePlayer(t *testing.T, s *Store[any], xp uint64, at time.Time) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase "+id.String()); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	p, err := s.GetUserStateByID(id)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	if _, err := s.SaveWithOptions(p, AwardXP(xp), WithClock(func() time.Time { return at })); err != nil {
+		t.Fatalf("SaveWithOptions() = %v", err)
+	}
+	return id
+}
+
+// checkXP reports whether the player with id holds want XP and level.
+func checkXP(t *testing.T, s *Store[any], id uuid.UUID, want uint64, wantLevel uint32) {
+	t.Helper()
+
+	xp, level, err := s.GetXPAndLevel(id)
+	if err != nil {
+		t.Fatalf("GetXPAndLevel() = %v", err)
+	}
+
+	if xp != want || level != wantLevel {
+		t.Errorf("xp %d, level %d; want %d, %d", xp, level, want, wantLevel)
+	}
+}
+
+func TestDecayInactivePlayers(t *testing.T) {
+	s := testStore[any](t)
+	cutoff := time.Now().Add(-7 * 24 * time.Hour).Truncate(time.Second)
+	day := 24 * time.Hour
+
+	// Inactive for 2.5 days beyond the cutoff, one hour beyond it, and active
+	inactive := activePlayer(t, s, 1_000, cutoff.Add(-day*5/2))
+	crossed := activePlayer(t, s, 1_000, cutoff.Add(-time.Hour))
+	active := activePlayer(t, s, 1_000, cutoff.Add(day))
+
+	// Only whole days beyond the cutoff decay, not the grace period before it
+	affected, err := s.DecayInactivePlayers(cutoff, 100)
+	if err != nil {
+		t.Fatalf("DecayInactivePlayers() = %v", err)
+	}
+
+	if affected != 1 {
+		t.Errorf("DecayInactivePlayers() = %d, want 1", affected)
+	}
+
+	checkXP(t, s, inactive, 800, 5)
+	checkXP(t, s, crossed, 1_000, 6)
+	checkXP(t, s, active, 1_000, 6)
+
+	// Running again with the same cutoff decays nothing
+	affected, err = s.DecayInactivePlayers(cutoff, 100)
+	if err != nil {
+		t.Fatalf("DecayInactivePlayers() = %v", err)
+	}
+
+	if affected != 0 {
+		t.Errorf("repeated DecayInactivePlayers() = %d, want 0", affected)
+	}
+	checkXP(t, s, inactive, 800, 5)
+
+	// A day later, each inactive player loses one more day, and the half
+	// day left over from the first run carries over
+	affected, err = s.DecayInactivePlayers(cutoff.Add(day), 100)
+	if err != nil {
+		t.Fatalf("DecayInactivePlayers() = %v", err)
+	}
+
+	if affected != 2 {
+		t.Errorf("DecayInactivePlayers() a day later = %d, want 2", affected)
+	}
+
+	checkXP(t, s, inactive, 700, 4)
+	checkXP(t, s, crossed, 900, 5)
+	checkXP(t, s, active, 1_000, 6)
+}
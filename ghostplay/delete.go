@@ -0,0 +1,186 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SoftDeletePlayer marks a player as deleted without removing the row.
+// Soft-deleted players are hidden from getters and leaderboards until restored.
+func SoftDeletePlayer(db *sql.DB, dbTableName string, id uuid.UUID) error {
+	return NewStore[any](db, dbTableName).SoftDeletePlayer(id)
+}
+
+// RestorePlayer clears the soft-delete marker on a player.
+func RestorePlayer(db *sql.DB, dbTableName string, id uuid.UUID) error {
+	return NewStore[any](db, dbTableName).RestorePlayer(id)
+}
+
+// SoftDeletePlayer marks a player as deleted without removing the row.
+// ErrPlayerNotFound is returned if no live player has the given UUID.
+func (s *Store[T]) SoftDeletePlayer(id uuid.UUID) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {deleted_at} = now()
+		WHERE {id} = $1 AND {deleted_at} IS NULL AND {season}
+		`)
+
+	err := s.do("soft_delete", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id)...)
+		if err != nil {
+			return fmt.Errorf("failed to soft delete player: %w", err)
+		}
+
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// RestorePlayer clears the soft-delete marker on a player.
+// ErrPlayerNotFound is returned if no soft-deleted player has the given UUID.
+func (s *Store[T]) RestorePlayer(id uuid.UUID) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {deleted_at} = NULL
+		WHERE {id} = $1 AND {deleted_at} IS NOT NULL AND {season}
+		`)
+
+	err := s.do("restore", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id)...)
+		if err != nil {
+			return fmt.Errorf("failed to restore player: %w", err)
+		}
+
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// requireRowAffected returns ErrPlayerNotFound when a statement matched no rows.
+func requireRowAffected(result sql.Result) error {
+	n, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to read affected rows: %w", err)
+	}
+
+	if n == 0 {
+		return ErrPlayerNotFound
+	}
+
+	return nil
+}
+
+// PurgeInactivePlayers permanently deletes players last updated before
+// inactiveSince and returns how many were removed.
+func PurgeInactivePlayers(db *sql.DB, dbTableName string, inactiveSince time.Time) (int64, error) {
+	return NewStore[any](db, dbTableName).PurgeInactivePlayers(inactiveSince)
+}
+
+// PurgeInactivePlayers permanently deletes players last updated before
+// inactiveSince, soft-deleted or not, and returns how many were removed.
+// It runs as a single statement and is safe to schedule; the
+// last_updated index keeps it from scanning the whole table. Phrases added
+// to purged players are removed from the Store's PhraseTable as well, while
+// their audit history is kept.
+//
+// For a grace period, soft-delete inactive players first and purge them
+// later with PurgeDeletedPlayers.
+func (s *Store[T]) PurgeInactivePlayers(inactiveSince time.Time) (int64, error) {
+	return s.purge("purge_inactive", "{last_updated} < $1", inactiveSince)
+}
+
+// PurgeDeletedPlayers permanently deletes players soft-deleted before
+// deletedBefore and returns how many were removed.
+func PurgeDeletedPlayers(db *sql.DB, dbTableName string, deletedBefore time.Time) (int64, error) {
+	return NewStore[any](db, dbTableName).PurgeDeletedPlayers(deletedBefore)
+}
+
+// PurgeDeletedPlayers permanently deletes players soft-deleted before
+// deletedBefore and returns how many were removed, ending the grace period
+// in which they could still be restored. Like PurgeInactivePlayers, it also
+// removes their added phrases.
+func (s *Store[T]) PurgeDeletedPlayers(deletedBefore time.Time) (int64, error) {
+	return s.purge("purge_deleted", "{deleted_at} < $1", deletedBefore)
+}
+
+// purge deletes the players matching where, along with their added phrases.
+func (s *Store[T]) purge(op, where string, arg any) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	where += " AND {season}"
+	query := s.expand(`
+		DELETE FROM {table}
+		WHERE ` + where)
+
+	if s.PhraseTable != "" {
+		phrases, err := s.phraseTable()
+		if err != nil {
+			return 0, err
+		}
+
+		query = s.expand(`
+		WITH purged AS (
+			DELETE FROM {table}
+			WHERE ` + where + `
+			RETURNING {id}
+		), phrases AS (
+			DELETE FROM ` + phrases + `
+			WHERE player_id IN (SELECT {id} FROM purged)
+				AND player_id NOT IN (SELECT {id} FROM {table} WHERE NOT ({season}))
+		)
+		SELECT COUNT(*) FROM purged`)
+	}
+
+	var purged int64
+	err := s.do(op, func(ctx context.Context) error {
+		if s.PhraseTable != "" {
+			return s.db.QueryRowContext(ctx, query, s.seasonArgs(arg)...).Scan(&purged)
+		}
+
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(arg)...)
+		if err != nil {
+			return err
+		}
+
+		purged, err = result.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge players: %w", err)
+	}
+
+	s.afterBulkSave(purged)
+	return purged, nil
+}
@@ -0,0 +1,92 @@
+package ghostplay
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+)
+
+// PlayerDiff describes how a player changed between two snapshots.
+type PlayerDiff struct {
+	XPDelta       int64  `json:"xp_delta"`
+	PreviousLevel uint32 `json:"previous_level"`
+	Level         uint32 `json:"level"`
+
+	// Flags maps each flag whose value changed to its new value.
+	Flags map[string]bool `json:"flags,omitempty"`
+
+	// ExtraDataChanged reports whether the extra data differs at all, and
+	// ExtraDataFields lists the top-level keys that differ, in sorted order,
+	// when both sides marshal to JSON objects.
+	ExtraDataChanged bool     `json:"extra_data_changed"`
+	ExtraDataFields  []string `json:"extra_data_fields,omitempty"`
+}
+
+// LeveledUp reports whether the level increased.
+func (d PlayerDiff) LeveledUp() bool {
+	return d.Level > d.PreviousLevel
+}
+
+// Diff describes the changes from p to other, e.g., a player before and after
+// a Save. A flag missing from a map, including a nil map, counts as false, so
+// a nil Flags map and one holding only false flags are equal. ExtraData is
+// compared by its marshaled JSON.
+func (p *PlayerState[T]) Diff(other *PlayerState[T]) (PlayerDiff, error) {
+	diff := PlayerDiff{
+		XPDelta:       int64(other.XP - p.XP),
+		PreviousLevel: p.Level,
+		Level:         other.Level,
+	}
+
+	for key, value := range p.Flags {
+		if other.Flags[key] != value {
+			if diff.Flags == nil {
+				diff.Flags = make(map[string]bool)
+			}
+			diff.Flags[key] = other.Flags[key]
+		}
+	}
+	for key, value := range other.Flags {
+		if _, seen := p.Flags[key]; !seen && value {
+			if diff.Flags == nil {
+				diff.Flags = make(map[string]bool)
+			}
+			diff.Flags[key] = value
+		}
+	}
+
+	before, err := marshalExtraData(JSONCodec{}, p.ExtraData)
+	if err != nil {
+		return PlayerDiff{}, err
+	}
+
+	after, err := marshalExtraData(JSONCodec{}, other.ExtraData)
+	if err != nil {
+		return PlayerDiff{}, err
+	}
+
+	if bytes.Equal(before, after) {
+		return diff, nil
+	}
+	diff.ExtraDataChanged = true
+
+	var beforeFields, afterFields map[string]json.RawMessage
+	if json.Unmarshal(before, &beforeFields) != nil || json.Unmarshal(after, &afterFields) != nil {
+		// Not both objects, so there are no fields to name
+		return diff, nil
+	}
+
+	for key, value := range beforeFields {
+		if !bytes.Equal(value, afterFields[key]) {
+			diff.ExtraDataFields = append(diff.ExtraDataFields, key)
+		}
+	}
+	for key := range afterFields {
+		if _, seen := beforeFields[key]; !seen {
+			diff.ExtraDataFields = append(diff.ExtraDataFields, key)
+		}
+	}
+	sort.Strings(diff.ExtraDataFields)
+
+	return diff, nil
+}
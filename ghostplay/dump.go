@@ -0,0 +1,102 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/google/uuid"
+)
+
+// dumpBatchSize is how many players DumpAll reads per query.
+const dumpBatchSize = 1000
+
+// DumpAll writes every player in the table to w as JSON lines, for backups
+// and offline analysis. Restore the dump with LoadAll.
+func DumpAll[T any](ctx context.Context, db *sql.DB, dbTableName string, w io.Writer) error {
+	return NewStore[T](db, dbTableName).DumpAll(ctx, w)
+}
+
+// LoadAll reads players written by DumpAll from r and imports each one,
+// returning how many were loaded.
+func LoadAll[T any](ctx context.Context, db *sql.DB, dbTableName string, r io.Reader) (int64, error) {
+	return NewStore[T](db, dbTableName).LoadAll(ctx, r)
+}
+
+// DumpAll writes every player in the Store's Season to w, soft-deleted ones
+// included, as one JSON object per line in the format of ExportPlayer.
+// Players are read in ID order in batches found by keyset, so memory stays
+// bounded however large the table is, and the dump stops with ctx's error
+// once ctx is done. Players written while the dump runs may or may not be
+// included, since each batch is read separately.
+func (s *Store[T]) DumpAll(ctx context.Context, w io.Writer) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {id} > $1 AND {season}
+		ORDER BY {id}
+		LIMIT $2`)
+
+	enc := json.NewEncoder(w)
+	cursor := uuid.Nil
+	for {
+		var players []*PlayerState[T]
+		err := s.doContext(ctx, "dump", func(ctx context.Context) error {
+			var err error
+			players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(cursor, dumpBatchSize)...)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range players {
+			if err := enc.Encode(p); err != nil {
+				return fmt.Errorf("failed to write player %s: %w", p.ID, err)
+			}
+		}
+
+		if len(players) < dumpBatchSize {
+			return nil
+		}
+		cursor = players[len(players)-1].ID
+	}
+}
+
+// LoadAll reads players written by DumpAll from r, one JSON object per line,
+// and imports each with ImportPlayer into the Store's Season, replacing
+// stored players with the same ID. Players are decoded and written one at a
+// time, so memory stays bounded, and loading stops with ctx's error once ctx
+// is done. Players are imported separately: on error, those before the
+// failing one remain loaded, and the error names the failing line.
+func (s *Store[T]) LoadAll(ctx context.Context, r io.Reader) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	dec := json.NewDecoder(r)
+	var loaded int64
+	for {
+		var data json.RawMessage
+		err := dec.Decode(&data)
+		if errors.Is(err, io.EOF) {
+			return loaded, nil
+		}
+
+		if err != nil {
+			return loaded, fmt.Errorf("%w: player %d: %v", ErrInvalidData, loaded+1, err)
+		}
+
+		if err := s.importPlayer(ctx, data); err != nil {
+			return loaded, fmt.Errorf("player %d: %w", loaded+1, err)
+		}
+		loaded++
+	}
+}
@@ -0,0 +1,168 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// ExportPlayer returns a player's full state serialized as JSON, for backups
+// and for copying players between environments with ImportPlayer.
+func ExportPlayer[T any](db *sql.DB, dbTableName string, id uuid.UUID) ([]byte, error) {
+	return NewStore[T](db, dbTableName).ExportPlayer(id)
+}
+
+// ExportPlayer returns a player's full state serialized as JSON, for backups
+// and for copying players between environments with ImportPlayer.
+//
+// ExtraData passes through T, so fields T doesn't declare are dropped. Use
+// json.RawMessage as T to export the stored extra data byte for byte.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) ExportPlayer(id uuid.UUID) ([]byte, error) {
+	state, err := s.GetUserStateByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal player: %w", err)
+	}
+
+	return data, nil
+}
+
+// ImportPlayer writes a player exported by ExportPlayer, replacing the stored
+// player with the same ID if there is one.
+func ImportPlayer[T any](db *sql.DB, dbTableName string, data []byte) error {
+	return NewStore[T](db, dbTableName).ImportPlayer(data)
+}
+
+// ImportPlayer writes a player exported by ExportPlayer, replacing the stored
+// player with the same ID in the Store's Season if there is one. Every
+// column is written as exported, including level, XP, last update, and
+// deletion time, so the level curve and the Store's starting values are not
+// applied.
+//
+// The phrase is written as-is; an export from a Store with a PhraseHasher
+// already holds the hash, so import it into a Store using the same hasher.
+// ErrPhraseTaken is returned if a different player already holds the phrase.
+func (s *Store[T]) ImportPlayer(data []byte) error {
+	return s.importPlayer(s.ctx, data)
+}
+
+// importPlayer is ImportPlayer bounded by ctx.
+func (s *Store[T]) importPlayer(ctx context.Context, data []byte) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	var p PlayerState[T]
+	if err := json.Unmarshal(data, &p); err != nil {
+		return fmt.Errorf("%w: failed to unmarshal player: %v", ErrInvalidData, err)
+	}
+
+	if p.ID == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if p.UserName == "" || p.Phrase == "" {
+		return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths(p.UserName, p.Phrase); err != nil {
+		return err
+	}
+
+	if err := s.checkFlags(p.Flags); err != nil {
+		return err
+	}
+
+	if p.Flags == nil {
+		p.Flags = make(map[string]bool)
+	}
+
+	if p.LastUpdated.IsZero() {
+		p.LastUpdated = s.now()
+	}
+
+	extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+	if err != nil {
+		return err
+	}
+
+	flags, err := marshalFlags(s.codec(), p.Flags)
+	if err != nil {
+		return err
+	}
+
+	// Update first, since older tables lack a unique key covering the season
+	update := s.expand(`
+		UPDATE {table}
+		SET {user_name} = $2,
+			{phrase} = $3,
+			{level} = $4,
+			{xp} = $5,
+			{last_updated} = $6,
+			{flags} = $7,
+			{extra_data} = $8,
+			{deleted_at} = $9,
+			{updated_by} = $10
+		WHERE {id} = $1 AND {season}
+		`)
+
+	insert := s.expand(`
+		INSERT INTO {table} ({id}, {user_name}, {phrase}, {level}, {xp},
+			{last_updated}, {flags}, {extra_data}, {deleted_at}, {updated_by}, {season_id})
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, {season_value})
+		`)
+
+	args := []any{
+		p.ID,
+		p.UserName,
+		p.Phrase,
+		p.Level,
+		p.XP,
+		p.LastUpdated,
+		flags,
+		extraData,
+		p.DeletedAt,
+		actor(p.UpdatedBy),
+	}
+
+	err = s.doContext(ctx, "import_player", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		err = execUpdate(ctx, tx, nil, update, s.seasonArgs(args...)...)
+		if errors.Is(err, ErrPlayerNotFound) {
+			_, err = tx.ExecContext(ctx, insert, s.seasonArgs(args...)...)
+		}
+
+		if hasSQLState(err, sqlStateUniqueViolation) {
+			return fmt.Errorf("%w: %q", ErrPhraseTaken, p.Phrase)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to import player: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit import: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(p.ID, SaveResult{XP: p.XP, Level: p.Level})
+	return nil
+}
@@ -0,0 +1,410 @@
+package ghostplay
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// checkExtraDataType reports whether JSON can be decoded into an ExtraData of
+// type T. Without this check, unsupported types surface as confusing decode
+// errors or zero values.
+func checkExtraDataType[T any]() error {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Interface:
+		// The empty interface decodes into maps, slices, and scalars
+		if t.NumMethod() == 0 {
+			return nil
+		}
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+	default:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s cannot be decoded from JSON; use a concrete struct, pointer, or map type", ErrExtraDataType, t)
+}
+
+// decodeExtraData unmarshals stored extra data into dst, rejecting keys T
+// doesn't declare when the Store is in strict mode.
+func (s *Store[T]) decodeExtraData(data []byte, dst *T) error {
+	if !s.StrictExtraData {
+		return s.codec().Unmarshal(data, dst)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	return dec.Decode(dst)
+}
+
+// emptyObject is the JSON stored for absent object-like extra data, matching
+// the column default.
+var emptyObject = []byte("{}")
+
+// isObjectType reports whether values of t encode as JSON objects: structs
+// and maps, possibly behind pointers, and interfaces, which decode objects
+// into maps. A nil t is a nil interface.
+func isObjectType(t reflect.Type) bool {
+	if t == nil {
+		return true
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch t.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return true
+	}
+	return false
+}
+
+// marshalExtraData encodes extra data for storage with c, wrapping failures
+// with ErrExtraDataMarshal and the offending type. A nil pointer, map, or
+// interface of an object-like type is stored as the empty object.
+func marshalExtraData(c Codec, extraData any) ([]byte, error) {
+	data, err := c.Marshal(extraData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: extra data of type %T: %w", ErrExtraDataMarshal, extraData, err)
+	}
+
+	if string(data) == "null" && isObjectType(reflect.TypeOf(extraData)) {
+		return emptyObject, nil
+	}
+	return data, nil
+}
+
+// marshalFlags encodes flags for storage with c, wrapping failures with
+// ErrExtraDataMarshal.
+func marshalFlags(c Codec, flags map[string]bool) ([]byte, error) {
+	data, err := c.Marshal(flags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: flags: %w", ErrExtraDataMarshal, err)
+	}
+	return data, nil
+}
+
+// defaultExtraData returns the extra data stored for a newly created player:
+// the Store's DefaultExtraData, or an empty object.
+func (s *Store[T]) defaultExtraData() ([]byte, error) {
+	if s.DefaultExtraData == nil {
+		return emptyObject, nil
+	}
+
+	extraData, err := marshalExtraData(s.codec(), s.DefaultExtraData())
+	if err != nil {
+		return nil, err
+	}
+	return extraData, nil
+}
+
+// GetRawExtraData returns a player's extra data as raw JSON, for tooling that
+// doesn't know the concrete ExtraData type.
+func GetRawExtraData(db *sql.DB, dbTableName string, id uuid.UUID) (json.RawMessage, error) {
+	return NewStore[any](db, dbTableName).GetRawExtraData(id)
+}
+
+// GetRawExtraData returns a player's extra data as raw JSON, for tooling that
+// doesn't know the concrete ExtraData type.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) GetRawExtraData(id uuid.UUID) (json.RawMessage, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		SELECT {extra_data}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	var extra []byte
+	err := s.do("get_raw_extra_data", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(id)...).Scan(&extra)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query extra data: %w", err)
+	}
+
+	return json.RawMessage(extra), nil
+}
+
+// ReplaceExtraData overwrites a player's extra data with data without
+// awarding XP, for clients that are authoritative over it.
+func ReplaceExtraData[T any](db *sql.DB, dbTableName string, id uuid.UUID, data T) error {
+	return NewStore[T](db, dbTableName).ReplaceExtraData(id, data)
+}
+
+// ReplaceExtraData overwrites a player's extra data with data and bumps
+// their last update time, leaving XP, level, and flags untouched, e.g.,
+// after a full sync from a client that is authoritative over it. Use Save to
+// award XP. data is checked with the Store's Validate first, and the
+// player's UpdatedBy is cleared.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) ReplaceExtraData(id uuid.UUID, data T) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if s.Validate != nil {
+		if err := s.Validate(data); err != nil {
+			return fmt.Errorf("%w: extra data: %w", ErrInvalidData, err)
+		}
+	}
+
+	extraData, err := marshalExtraData(s.codec(), data)
+	if err != nil {
+		return err
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {extra_data} = $2,
+			{last_updated} = $3,
+			{updated_by} = NULL
+		WHERE {id} = $1 AND {live}
+		RETURNING {xp}, {level}
+		`)
+
+	var result SaveResult
+	err = s.do("replace_extra_data", func(ctx context.Context) error {
+		err := s.db.QueryRowContext(ctx, query, s.seasonArgs(id, extraData, s.now())...).Scan(&result.XP, &result.Level)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlayerNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to replace extra data: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	result.PreviousXP, result.PreviousLevel = result.XP, result.Level
+	s.afterSave(id, result)
+	return nil
+}
+
+// GetPlayersByExtraDataField returns up to limit players whose extra data has
+// value at jsonPath, a dot-separated path such as "clan_id" or "guild.name".
+func GetPlayersByExtraDataField[T any](db *sql.DB, dbTableName, jsonPath string, value any, limit int) ([]PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetPlayersByExtraDataField(jsonPath, value, limit)
+}
+
+// GetPlayersByExtraDataField returns up to limit players whose extra data has
+// value at jsonPath, a dot-separated path such as "clan_id" or "guild.name".
+// Players are ordered by XP, highest first, and an empty slice is returned
+// when nobody matches.
+//
+// JSONB values are compared as text, so value must be a string, number, or
+// boolean; a string matches the stored string itself, while numbers and
+// booleans match their JSON spelling (e.g., 42 or true). A jsonPath naming
+// one of the Store's PromotedFields is matched against its column instead,
+// with value cast to the column's type, so the column's index can be used.
+func (s *Store[T]) GetPlayersByExtraDataField(jsonPath string, value any, limit int) ([]PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	path, err := parseJSONPath(jsonPath)
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := jsonText(value)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkLimit("limit", limit); err != nil {
+		return nil, err
+	}
+
+	match := "{extra_data} #>> $1::text[] = $2"
+	args := []any{textArray(path), text}
+	if f, ok := s.promoted(jsonPath); ok {
+		column, err := f.column()
+		if err != nil {
+			return nil, err
+		}
+
+		typ, err := f.sqlType()
+		if err != nil {
+			return nil, err
+		}
+
+		match = column + " = $1::" + typ
+		args = []any{text}
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE ` + match + ` AND {live}
+		ORDER BY {xp} DESC, {id}
+		LIMIT $` + strconv.Itoa(len(args)+1))
+
+	var players []*PlayerState[T]
+	err = s.do("get_by_extra_data", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(append(args, limit)...)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return derefPlayers(players), nil
+}
+
+// parseJSONPath splits a dot-separated JSON path into its segments.
+func parseJSONPath(jsonPath string) ([]string, error) {
+	if jsonPath == "" {
+		return nil, fmt.Errorf("%w: json path cannot be empty", ErrInvalidData)
+	}
+
+	path := strings.Split(jsonPath, ".")
+	for _, segment := range path {
+		if segment == "" {
+			return nil, fmt.Errorf("%w: json path %q has an empty segment", ErrInvalidData, jsonPath)
+		}
+	}
+	return path, nil
+}
+
+// jsonText returns the text Postgres' ->> and #>> operators produce for a
+// scalar JSON value.
+func jsonText(value any) (string, error) {
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("%w: unsupported value: %v", ErrInvalidData, err)
+	}
+
+	switch {
+	case len(raw) > 0 && raw[0] == '"':
+		var text string
+		if err := json.Unmarshal(raw, &text); err != nil {
+			return "", fmt.Errorf("%w: unsupported value: %v", ErrInvalidData, err)
+		}
+		return text, nil
+	case len(raw) > 0 && (raw[0] == '{' || raw[0] == '[') || string(raw) == "null":
+		return "", fmt.Errorf("%w: value must be a string, number, or boolean", ErrInvalidData)
+	}
+	return string(raw), nil
+}
+
+// derefPlayers converts scanned players to the value slice returned by list
+// queries, keeping it non-nil so empty results encode as [].
+func derefPlayers[T any](players []*PlayerState[T]) []PlayerState[T] {
+	states := make([]PlayerState[T], len(players))
+	for i, p := range players {
+		states[i] = *p
+	}
+	return states
+}
+
+// IncrementExtraDataNumber atomically adds delta to the integer stored at
+// jsonPath in a player's extra data and returns the new value.
+func IncrementExtraDataNumber(db *sql.DB, dbTableName string, id uuid.UUID, jsonPath string, delta int64) (int64, error) {
+	return NewStore[any](db, dbTableName).IncrementExtraDataNumber(id, jsonPath, delta)
+}
+
+// IncrementExtraDataNumber atomically adds delta to the integer stored at
+// jsonPath in a player's extra data and returns the new value. The arithmetic
+// runs server-side so concurrent increments are never lost.
+//
+// A missing field is treated as zero and created, though for nested paths
+// its parent object must already exist. ErrInvalidData is returned if the
+// field holds anything other than an integer, and ErrPlayerNotFound if no
+// player has the given UUID.
+func (s *Store[T]) IncrementExtraDataNumber(id uuid.UUID, jsonPath string, delta int64) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return 0, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	path, err := parseJSONPath(jsonPath)
+	if err != nil {
+		return 0, err
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {extra_data} = jsonb_set(
+			COALESCE({extra_data}, '{}'::jsonb),
+			$2::text[],
+			to_jsonb(COALESCE(({extra_data} #>> $2::text[])::bigint, 0) + $3)
+		)
+		WHERE {id} = $1 AND {live}
+			AND ({extra_data} #> $2::text[] IS NULL
+				OR (jsonb_typeof({extra_data} #> $2::text[]) = 'number'
+					AND {extra_data} #>> $2::text[] ~ '^-?[0-9]+$'))
+		RETURNING ({extra_data} #>> $2::text[])::bigint
+		`)
+
+	exists := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {live})
+		`)
+
+	var value sql.NullInt64
+	err = s.do("increment_extra_data", func(ctx context.Context) error {
+		err := s.db.QueryRowContext(ctx, query, s.seasonArgs(id, textArray(path), delta)...).Scan(&value)
+		if !errors.Is(err, sql.ErrNoRows) {
+			if err != nil {
+				return fmt.Errorf("failed to increment extra data: %w", err)
+			}
+			return nil
+		}
+
+		// Nothing matched; tell a missing player apart from a bad field
+		var found bool
+		if err := s.db.QueryRowContext(ctx, exists, s.seasonArgs(id)...).Scan(&found); err != nil {
+			return fmt.Errorf("failed to check player existence: %w", err)
+		}
+
+		if !found {
+			return ErrPlayerNotFound
+		}
+		return fmt.Errorf("%w: extra data field %q is not an integer", ErrInvalidData, jsonPath)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if !value.Valid {
+		return 0, fmt.Errorf("%w: parent of extra data field %q does not exist", ErrInvalidData, jsonPath)
+	}
+
+	s.afterSave(id, SaveResult{})
+	return value.Int64, nil
+}
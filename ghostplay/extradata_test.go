@@ -0,0 +1,195 @@
+package ghostplay
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// roundTrip saves a player holding extraData in a MemoryStore and returns the
+// ExtraData read back.
+func roundTrip[T any](t *testing.T, extraData T) T {
+	t.Helper()
+
+	m := NewMemoryStore[T](nil)
+	p := NewPlayerState[T]("player", "phrase")
+	p.ExtraData = extraData
+	if _, err := m.Save(p, 0); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	got, err := m.GetUserStateByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+	return got.ExtraData
+}
+
+func TestExtraDataRoundTrip(t *testing.T) {
+	want := extraData{Class: "mage", Gold: 7}
+	if got := roundTrip(t, want); got != want {
+		t.Errorf("struct ExtraData = %+v, want %+v", got, want)
+	}
+
+	if got := roundTrip(t, &want); got == nil || *got != want {
+		t.Errorf("pointer ExtraData = %+v, want &%+v", got, want)
+	}
+
+	m := map[string]any{"class": "mage", "gold": float64(7)}
+	if got := roundTrip(t, m); !reflect.DeepEqual(got, m) {
+		t.Errorf("map ExtraData = %v, want %v", got, m)
+	}
+}
+
+func TestExtraDataUnsupportedType(t *testing.T) {
+	s := NewStore[fmt.Stringer](nil, "players")
+	p := &PlayerState[fmt.Stringer]{}
+
+	err := s.decodeJSONColumns(p, nil, []byte(`{"class":"mage"}`))
+	if !errors.Is(err, ErrExtraDataType) {
+		t.Errorf("decodeJSONColumns() = %v, want ErrExtraDataType", err)
+	}
+}
+
+func TestStrictExtraData(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored string
+		strict bool
+		want   extraData
+		valid  bool
+	}{
+		{"unknown key ignored", `{"class":"mage","gold":7,"mana":30}`, false, extraData{Class: "mage", Gold: 7}, true},
+		{"unknown key rejected", `{"class":"mage","gold":7,"mana":30}`, true, extraData{}, false},
+		{"missing field defaults", `{"class":"mage"}`, false, extraData{Class: "mage"}, true},
+		{"missing field defaults in strict mode", `{"class":"mage"}`, true, extraData{Class: "mage"}, true},
+	}
+
+	for _, tt := range tests {
+		s := NewStore[extraData](nil, "players")
+		s.StrictExtraData = tt.strict
+
+		row := playerRow(uuid.NewString())
+		row[7] = []byte(tt.stored)
+
+		p, err := s.scanPlayer(row)
+		if !tt.valid {
+			if err == nil {
+				t.Errorf("%s: scanPlayer() = %+v, want an error", tt.name, p.ExtraData)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("%s: scanPlayer() = %v", tt.name, err)
+			continue
+		}
+
+		if p.ExtraData != tt.want {
+			t.Errorf("%s: ExtraData = %+v, want %+v", tt.name, p.ExtraData, tt.want)
+		}
+	}
+}
+
+func TestStrictExtraDataAcceptsMapKeys(t *testing.T) {
+	s := NewStore[map[string]any](nil, "players")
+	s.StrictExtraData = true
+
+	row := playerRow(uuid.NewString())
+	row[7] = []byte(`{"anything":1}`)
+
+	p, err := s.scanPlayer(row)
+	if err != nil {
+		t.Fatalf("scanPlayer() = %v", err)
+	}
+
+	if p.ExtraData["anything"] != float64(1) {
+		t.Errorf("ExtraData = %v, want anything: 1", p.ExtraData)
+	}
+}
+
+func TestNilExtraData(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		want  string
+	}{
+		{"nil pointer", (*extraData)(nil), "{}"},
+		{"nil map", map[string]any(nil), "{}"},
+		{"nil interface", nil, "{}"},
+		{"nil slice", []string(nil), "null"},
+	}
+
+	for _, tt := range tests {
+		data, err := marshalExtraData(JSONCodec{}, tt.value)
+		if err != nil {
+			t.Errorf("%s: marshalExtraData() = %v", tt.name, err)
+			continue
+		}
+
+		if string(data) != tt.want {
+			t.Errorf("%s: marshalExtraData() = %s, want %s", tt.name, data, tt.want)
+		}
+	}
+}
+
+func TestNilPointerExtraDataRoundTrip(t *testing.T) {
+	if got := roundTrip[*extraData](t, nil); got == nil || *got != (extraData{}) {
+		t.Errorf("nil pointer ExtraData read back as %v, want a pointer to the zero value", got)
+	}
+
+	if got := roundTrip[map[string]any](t, nil); got == nil || len(got) != 0 {
+		t.Errorf("nil map ExtraData read back as %v, want an empty map", got)
+	}
+
+	if got := roundTrip[[]string](t, nil); got != nil {
+		t.Errorf("nil slice ExtraData read back as %v, want nil", got)
+	}
+}
+
+func TestStoredNullExtraData(t *testing.T) {
+	// Rows written before nil extra data was normalized hold null
+	s := NewStore[*extraData](nil, "players")
+
+	row := playerRow(uuid.NewString())
+	row[7] = []byte("null")
+
+	p, err := s.scanPlayer(row)
+	if err != nil {
+		t.Fatalf("scanPlayer() = %v", err)
+	}
+
+	if p.ExtraData == nil || *p.ExtraData != (extraData{}) {
+		t.Errorf("ExtraData = %v, want a pointer to the zero value", p.ExtraData)
+	}
+}
+
+func TestNilPointerExtraDataStored(t *testing.T) {
+	s := testStore[*extraData](t)
+
+	p := NewPlayerState[*extraData]("player", "phrase")
+	if _, err := s.Save(p, 0); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	raw, err := s.GetRawExtraData(p.ID)
+	if err != nil {
+		t.Fatalf("GetRawExtraData() = %v", err)
+	}
+
+	if string(raw) != "{}" {
+		t.Errorf("stored extra data = %s, want {}", raw)
+	}
+
+	got, err := s.GetUserStateByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	if got.ExtraData == nil || *got.ExtraData != (extraData{}) {
+		t.Errorf("ExtraData = %v, want a pointer to the zero value", got.ExtraData)
+	}
+}
@@ -0,0 +1,136 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strconv"
+	"testing"
+)
+
+// fakeHandler answers a query sent to a fakeDB with the rows it returns.
+// Exec statements report one affected row per returned row.
+type fakeHandler func(query string, args []driver.NamedValue) ([][]driver.Value, error)
+
+// fakeDB returns a database whose queries are answered by handle, for
+// testing Store methods without a Postgres server. A nil handle fails every
+// query.
+func fakeDB(t *testing.T, handle fakeHandler) *sql.DB {
+	t.Helper()
+
+	if handle == nil {
+		handle = func(query string, _ []driver.NamedValue) ([][]driver.Value, error) {
+			t.Errorf("unexpected query: %s", query)
+			return nil, errors.New("unexpected query")
+		}
+	}
+
+	db := sql.OpenDB(fakeConnector{handle: handle})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// fakeConnector opens fakeConns sharing one handler.
+type fakeConnector struct {
+	handle fakeHandler
+}
+
+func (c fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	return &fakeConn{handle: c.handle}, nil
+}
+
+func (c fakeConnector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+// fakeDriver is only reachable through fakeConnector.
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fake driver: use fakeDB")
+}
+
+// fakeConn runs every statement through its handler; transactions are
+// accepted but don't isolate anything.
+type fakeConn struct {
+	handle fakeHandler
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("fake driver: prepared statements are not supported")
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return fakeTx{}, nil
+}
+
+// CheckNamedValue passes arguments to the handler as given.
+func (c *fakeConn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c *fakeConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.handle(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{rows: rows}, nil
+}
+
+func (c *fakeConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := c.handle(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(len(rows)), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+// fakeRows returns rows whose columns are named by position.
+type fakeRows struct {
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	if len(r.rows) == 0 {
+		return nil
+	}
+
+	columns := make([]string, len(r.rows[0]))
+	for i := range columns {
+		columns[i] = "column" + strconv.Itoa(i+1)
+	}
+	return columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
@@ -0,0 +1,107 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// PlayerFields selects which parts of a player GetUserStateByIDFields loads.
+// Combine them with |, e.g., WithXP|WithFlags.
+type PlayerFields uint
+
+const (
+	// WithXP loads XP and Level.
+	WithXP PlayerFields = 1 << iota
+
+	// WithLastUpdated loads LastUpdated.
+	WithLastUpdated
+
+	// WithFlags loads Flags.
+	WithFlags
+
+	// WithExtraData loads ExtraData.
+	WithExtraData
+)
+
+// GetUserStateByIDFields loads only the selected parts of a player, leaving
+// the rest at their zero values.
+func GetUserStateByIDFields[T any](db *sql.DB, dbTableName string, id uuid.UUID, fields PlayerFields) (*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetUserStateByIDFields(id, fields)
+}
+
+// GetUserStateByIDFields is like GetUserStateByID but selects and decodes
+// only the given fields, e.g., to skip unmarshaling extra data on screens
+// that don't show it. ID and UserName are always loaded; every other field,
+// including Phrase, stays at its zero value unless selected, so Flags is nil
+// without WithFlags. Pass the result to Save only when every field was
+// loaded, since Save writes them all.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) GetUserStateByIDFields(id uuid.UUID, fields PlayerFields) (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	var state PlayerState[T]
+	var rawID string
+	var flagsJSON, extraJSON []byte
+	columns := []string{"{id}", "{user_name}"}
+	dest := []any{&rawID, &state.UserName}
+
+	if fields&WithXP != 0 {
+		columns = append(columns, "{xp}", "{level}")
+		dest = append(dest, &state.XP, &state.Level)
+	}
+
+	if fields&WithLastUpdated != 0 {
+		columns = append(columns, "{last_updated}")
+		dest = append(dest, &state.LastUpdated)
+	}
+
+	if fields&WithFlags != 0 {
+		state.Flags = make(map[string]bool)
+		columns = append(columns, "{flags}")
+		dest = append(dest, &flagsJSON)
+	}
+
+	if fields&WithExtraData != 0 {
+		columns = append(columns, "{extra_data}")
+		dest = append(dest, &extraJSON)
+	}
+
+	query := s.expand(`
+		SELECT ` + strings.Join(columns, ", ") + `
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	err := s.do("get_by_id_fields", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(id)...).Scan(dest...)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player data: %w", err)
+	}
+
+	state.ID, err = parseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.decodeJSONColumns(&state, flagsJSON, extraJSON); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
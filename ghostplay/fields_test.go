@@ -0,0 +1,89 @@
+package ghostplay
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// extraData is a typical ExtraData type.
+type extraData struct {
+	Class string `json:"class"`
+	Gold  int    `json:"gold"`
+}
+
+// selectedColumns answers a query with the columns it selects from a stored
+// player.
+func selectedColumns(id uuid.UUID, lastUpdated time.Time) fakeHandler {
+	stored := map[string]driver.Value{
+		`"id"`:           id.String(),
+		`"user_name"`:    "player",
+		`"xp"`:           int64(450),
+		`"level"`:        int64(3),
+		`"last_updated"`: lastUpdated,
+		`"flags"`:        []byte(`{"premium":true}`),
+		`"extra_data"`:   []byte(`{"class":"mage","gold":7}`),
+	}
+
+	return func(query string, _ []driver.NamedValue) ([][]driver.Value, error) {
+		list, _, _ := strings.Cut(strings.TrimPrefix(strings.TrimSpace(query), "SELECT "), "FROM")
+
+		var row []driver.Value
+		for _, column := range strings.Split(list, ",") {
+			value, ok := stored[strings.TrimSpace(column)]
+			if !ok {
+				return nil, fmt.Errorf("unexpected column %s", column)
+			}
+			row = append(row, value)
+		}
+		return [][]driver.Value{row}, nil
+	}
+}
+
+func TestGetUserStateByIDFields(t *testing.T) {
+	id := uuid.New()
+	lastUpdated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := NewStore[extraData](fakeDB(t, selectedColumns(id, lastUpdated)), "players")
+
+	tests := []struct {
+		name   string
+		fields PlayerFields
+		want   PlayerState[extraData]
+	}{
+		{"none", 0, PlayerState[extraData]{}},
+		{"xp", WithXP, PlayerState[extraData]{XP: 450, Level: 3}},
+		{"last updated", WithLastUpdated, PlayerState[extraData]{LastUpdated: lastUpdated}},
+		{"flags", WithFlags, PlayerState[extraData]{Flags: map[string]bool{"premium": true}}},
+		{"extra data", WithExtraData, PlayerState[extraData]{ExtraData: extraData{Class: "mage", Gold: 7}}},
+		{"all", WithXP | WithLastUpdated | WithFlags | WithExtraData, PlayerState[extraData]{
+			XP:          450,
+			Level:       3,
+			LastUpdated: lastUpdated,
+			Flags:       map[string]bool{"premium": true},
+			ExtraData:   extraData{Class: "mage", Gold: 7},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := s.GetUserStateByIDFields(id, tt.fields)
+		if err != nil {
+			t.Fatalf("%s: GetUserStateByIDFields() = %v", tt.name, err)
+		}
+
+		want := tt.want
+		want.ID = id
+		want.UserName = "player"
+		if !reflect.DeepEqual(*got, want) {
+			t.Errorf("%s: GetUserStateByIDFields() = %+v, want %+v", tt.name, *got, want)
+		}
+
+		if tt.fields&WithFlags == 0 && got.Flags != nil {
+			t.Errorf("%s: Flags = %v, want nil when not selected", tt.name, got.Flags)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// FlagFilter selects players whose flags hold every given value, e.g.,
+// FlagFilter{"is_premium": true, "tutorial_completed": true}. A flag a player
+// doesn't have counts as false. An empty filter matches every player.
+type FlagFilter map[string]bool
+
+// where returns the filter as a SQL predicate over the Store's flags column
+// whose placeholders are numbered from $next, along with their arguments.
+func (f FlagFilter) where(flagsColumn string, next int) (string, []any, error) {
+	if len(f) == 0 {
+		return "TRUE", nil, nil
+	}
+
+	keys := make([]string, 0, len(f))
+	for key := range f {
+		if key == "" {
+			return "", nil, fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, len(keys))
+	args := make([]any, 0, 2*len(keys))
+	for i, key := range keys {
+		conditions[i] = fmt.Sprintf("COALESCE((%s ->> $%d)::boolean, false) = $%d", flagsColumn, next, next+1)
+		args = append(args, key, f[key])
+		next += 2
+	}
+	return "(" + strings.Join(conditions, " AND ") + ")", args, nil
+}
+
+// GetLeaderboardByFlags fetches the top users by XP among players matching
+// filter.
+func GetLeaderboardByFlags(db *sql.DB, dbTableName string, filter FlagFilter, limit int) ([]Leader, error) {
+	return NewStore[any](db, dbTableName).GetLeaderboardByFlags(filter, limit)
+}
+
+// GetLeaderboardByFlags fetches the top users by XP among players matching
+// filter. Players with equal XP are ordered by the Store's Tiebreaker.
+func (s *Store[T]) GetLeaderboardByFlags(filter FlagFilter, limit int) ([]Leader, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	where, args, err := filter.where(s.quotedColumns().Flags, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {user_name}, {level}, {xp}
+		FROM {table}
+		WHERE {live} AND ` + where + `
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $1`)
+
+	var users []Leader
+	err = s.do("leaderboard_by_flags", func(ctx context.Context) error {
+		var err error
+		users, err = queryLeaders(ctx, s.reader(), query, s.seasonArgs(append([]any{limit}, args...)...)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// CountPlayers returns how many players match filter.
+func CountPlayers(db *sql.DB, dbTableName string, filter FlagFilter) (int64, error) {
+	return NewStore[any](db, dbTableName).CountPlayers(filter)
+}
+
+// CountPlayers returns how many players match filter; an empty filter counts
+// every player.
+func (s *Store[T]) CountPlayers(filter FlagFilter) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	where, args, err := filter.where(s.quotedColumns().Flags, 1)
+	if err != nil {
+		return 0, err
+	}
+
+	query := s.expand(`
+		SELECT COUNT(*)
+		FROM {table}
+		WHERE {live} AND ` + where)
+
+	var count int64
+	err = s.do("count_players", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(args...)...).Scan(&count)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to count players: %w", err)
+	}
+
+	return count, nil
+}
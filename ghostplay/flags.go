@@ -0,0 +1,356 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/google/uuid"
+)
+
+// GetFlags returns a player's flags without reading their XP, level, or
+// extra data.
+func GetFlags(db *sql.DB, dbTableName string, id uuid.UUID) (map[string]bool, error) {
+	return NewStore[any](db, dbTableName).GetFlags(id)
+}
+
+// GetFlags returns a player's flags without reading their XP, level, or
+// extra data. A player with no flags stored gets an empty, non-nil map.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) GetFlags(id uuid.UUID) (map[string]bool, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		SELECT {flags}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	var flagsJSON []byte
+	err := s.do("get_flags", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(id)...).Scan(&flagsJSON)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query flags: %w", err)
+	}
+
+	flags := make(map[string]bool)
+	if len(flagsJSON) > 0 {
+		if err := s.codec().Unmarshal(flagsJSON, &flags); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal flags: %w", err)
+		}
+	}
+
+	// A stored JSON null decodes to a nil map
+	if flags == nil {
+		flags = make(map[string]bool)
+	}
+	return flags, nil
+}
+
+// ClearAllFlags resets a player's flags to an empty set without touching
+// XP, level, or extra data.
+func ClearAllFlags(db *sql.DB, dbTableName string, id uuid.UUID) error {
+	return NewStore[any](db, dbTableName).ClearAllFlags(id)
+}
+
+// SetFlagDirect sets a single flag on a player without touching XP, level,
+// extra data, or the player's other flags.
+func SetFlagDirect(db *sql.DB, dbTableName string, id uuid.UUID, key string, value bool) error {
+	return NewStore[any](db, dbTableName).SetFlagDirect(id, key, value)
+}
+
+// ClearAllFlags resets a player's flags to an empty set without touching
+// XP, level, or extra data.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) ClearAllFlags(id uuid.UUID) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {flags} = '{}'::jsonb
+		WHERE {id} = $1 AND {live}
+		`)
+
+	err := s.do("clear_flags", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id)...)
+		if err != nil {
+			return fmt.Errorf("failed to clear flags: %w", err)
+		}
+
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// SetFlagDirect sets a single flag on a player without touching XP, level,
+// extra data, or the player's other flags.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) SetFlagDirect(id uuid.UUID, key string, value bool) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if key == "" {
+		return fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkFlagKey(key); err != nil {
+		return err
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {flags} = jsonb_set(COALESCE({flags}, '{}'::jsonb), ARRAY[$2::text], to_jsonb($3::boolean))
+		WHERE {id} = $1 AND {live}
+		`)
+
+	err := s.do("set_flag", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id, key, value)...)
+		if err != nil {
+			return fmt.Errorf("failed to set flag: %w", err)
+		}
+
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// CompareAndSwapFlag atomically sets a player's flag to newValue only if it
+// currently equals oldValue, reporting whether the swap happened.
+func CompareAndSwapFlag(db *sql.DB, dbTableName string, id uuid.UUID, key string, oldValue, newValue bool) (bool, error) {
+	return NewStore[any](db, dbTableName).CompareAndSwapFlag(id, key, oldValue, newValue)
+}
+
+// CompareAndSwapFlag atomically sets a player's flag to newValue only if it
+// currently equals oldValue, reporting whether the swap happened. A flag that
+// isn't set counts as false, so a false-to-true swap claims an unset flag.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) CompareAndSwapFlag(id uuid.UUID, key string, oldValue, newValue bool) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return false, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if key == "" {
+		return false, fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkFlagKey(key); err != nil {
+		return false, err
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {flags} = jsonb_set(COALESCE({flags}, '{}'::jsonb), ARRAY[$2::text], to_jsonb($4::boolean))
+		WHERE {id} = $1 AND {live}
+			AND COALESCE(({flags}->>$2::text)::boolean, false) = $3::boolean
+		`)
+
+	exists := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {live})
+		`)
+
+	var swapped bool
+	err := s.do("compare_and_swap_flag", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id, key, oldValue, newValue)...)
+		if err != nil {
+			return fmt.Errorf("failed to swap flag: %w", err)
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+
+		swapped = n > 0
+		if swapped {
+			return nil
+		}
+
+		// Tell a lost race apart from a missing player
+		var found bool
+		if err := s.db.QueryRowContext(ctx, exists, s.seasonArgs(id)...).Scan(&found); err != nil {
+			return fmt.Errorf("failed to check player existence: %w", err)
+		}
+
+		if !found {
+			return ErrPlayerNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if swapped {
+		s.afterSave(id, SaveResult{})
+	}
+	return swapped, nil
+}
+
+// SetFlagForPlayers sets a single flag on every given player in one statement
+// and returns how many players were updated.
+func SetFlagForPlayers(db *sql.DB, dbTableName string, ids []uuid.UUID, key string, value bool) (int64, error) {
+	return NewStore[any](db, dbTableName).SetFlagForPlayers(ids, key, value)
+}
+
+// SetFlagForPlayers sets a single flag on every given player in one statement
+// and returns how many players were updated, e.g., for a feature rollout.
+// IDs that match no player are ignored, and an empty ids returns 0 without
+// querying the database.
+func (s *Store[T]) SetFlagForPlayers(ids []uuid.UUID, key string, value bool) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if key == "" {
+		return 0, fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkFlagKey(key); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {flags} = jsonb_set(COALESCE({flags}, '{}'::jsonb), ARRAY[$2::text], to_jsonb($3::boolean))
+		WHERE {id} = ANY($1::uuid[]) AND {live}
+		RETURNING {id}
+		`)
+
+	// The updated players are returned so AfterSave is called for each
+	var updated []uuid.UUID
+	err := s.do("set_flag_for_players", func(ctx context.Context) error {
+		var err error
+		updated, err = queryIDs(ctx, s.db, query, s.seasonArgs(uuidArray(ids), key, value)...)
+		if err != nil {
+			return fmt.Errorf("failed to set flags: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, id := range updated {
+		s.afterSave(id, SaveResult{})
+	}
+
+	return int64(len(updated)), nil
+}
+
+// GetPlayersByFlag returns up to limit players whose flag key is set to value.
+func GetPlayersByFlag[T any](db *sql.DB, dbTableName, key string, value bool, limit int) ([]PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetPlayersByFlag(key, value, limit)
+}
+
+// GetPlayersByFlag returns up to limit players whose flag key is set to value,
+// ordered by XP, highest first. A player without the flag counts as having it
+// set to false. An empty slice is returned when nobody matches.
+func (s *Store[T]) GetPlayersByFlag(key string, value bool, limit int) ([]PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if key == "" {
+		return nil, fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+	}
+
+	if err := checkLimit("limit", limit); err != nil {
+		return nil, err
+	}
+
+	where, args, err := FlagFilter{key: value}.where(s.quotedColumns().Flags, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {live} AND ` + where + `
+		ORDER BY {xp} DESC, {id}
+		LIMIT $1`)
+
+	var players []*PlayerState[T]
+	err = s.do("get_by_flag", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(append([]any{limit}, args...)...)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return derefPlayers(players), nil
+}
+
+// checkFlagKey reports whether key may be written under the Store's
+// AllowedFlags.
+func (s *Store[T]) checkFlagKey(key string) error {
+	if len(s.AllowedFlags) == 0 || slices.Contains(s.AllowedFlags, key) {
+		return nil
+	}
+	return fmt.Errorf("%w: flag %q is not allowed", ErrInvalidData, key)
+}
+
+// checkFlags reports whether every key in flags may be written under the
+// Store's AllowedFlags, naming the first disallowed key in sorted order.
+func (s *Store[T]) checkFlags(flags map[string]bool) error {
+	if len(s.AllowedFlags) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(flags))
+	for key := range flags {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		if err := s.checkFlagKey(key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
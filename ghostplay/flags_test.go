@@ -0,0 +1,78 @@
+package ghostplay
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestAllowedFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		allowed []string
+		key     string
+		valid   bool
+	}{
+		{"unrestricted", nil, "tutorual_done", true},
+		{"allowed key", []string{"tutorial_done", "premium"}, "premium", true},
+		{"unknown key", []string{"tutorial_done", "premium"}, "tutorual_done", false},
+	}
+
+	for _, tt := range tests {
+		s := NewStore[any](nil, "players")
+		s.AllowedFlags = tt.allowed
+		m := NewMemoryStore(s)
+
+		p := NewPlayerState[any]("player", "phrase")
+		p.Flags[tt.key] = true
+		_, err := m.Save(p, 0)
+		checkAllowed(t, tt.name+": Save()", err, tt.valid)
+
+		id := uuid.New()
+		if err := m.InitPlayer(id, "other", "other phrase"); err != nil {
+			t.Fatalf("%s: InitPlayer() = %v", tt.name, err)
+		}
+		checkAllowed(t, tt.name+": SetFlagDirect()", m.SetFlagDirect(id, tt.key, true), tt.valid)
+
+		flags, err := m.GetFlags(id)
+		if err != nil {
+			t.Fatalf("%s: GetFlags() = %v", tt.name, err)
+		}
+
+		if flags[tt.key] != tt.valid {
+			t.Errorf("%s: flag %q = %t after SetFlagDirect, want %t", tt.name, tt.key, flags[tt.key], tt.valid)
+		}
+	}
+}
+
+func TestAllowedFlagsCheckedBeforeQuerying(t *testing.T) {
+	s := NewStore[any](fakeDB(t, nil), "players")
+	s.AllowedFlags = []string{"premium"}
+	id := uuid.New()
+
+	checkAllowed(t, "SetFlagDirect()", s.SetFlagDirect(id, "beta", true), false)
+
+	_, err := s.CompareAndSwapFlag(id, "beta", false, true)
+	checkAllowed(t, "CompareAndSwapFlag()", err, false)
+
+	_, err = s.SetFlagForPlayers([]uuid.UUID{id}, "beta", true)
+	checkAllowed(t, "SetFlagForPlayers()", err, false)
+
+	_, err = s.AwardXPAndSetFlags(id, 10, map[string]bool{"premium": true, "beta": true})
+	checkAllowed(t, "AwardXPAndSetFlags()", err, false)
+}
+
+// checkAllowed reports whether err is what a write of an allowed, or a
+// disallowed, flag should return.
+func checkAllowed(t *testing.T, call string, err error, valid bool) {
+	t.Helper()
+
+	if valid && err != nil {
+		t.Errorf("%s = %v, want nil", call, err)
+	}
+
+	if !valid && !errors.Is(err, ErrInvalidData) {
+		t.Errorf("%s = %v, want ErrInvalidData", call, err)
+	}
+}
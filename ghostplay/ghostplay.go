@@ -1,28 +1,68 @@
 package ghostplay
 
 import (
+	"context"
 	"database/sql"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"log"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// Common errors that can be checked
+// Common errors that can be checked with errors.Is.
+// Every input a function rejects wraps ErrInvalidData, a missing player wraps
+// ErrPlayerNotFound, and a nil connection wraps ErrDatabaseConnection, so
+// callers can classify failures without matching messages. An operation
+// canceled or timed out by its context fails with an error matching
+// context.Canceled or context.DeadlineExceeded, never ErrDatabaseConnection.
 var (
 	ErrDatabaseConnection = errors.New("database connection error")
 	ErrPlayerNotFound     = errors.New("player not found")
 	ErrInvalidData        = errors.New("invalid player data")
+	ErrStoreClosed        = errors.New("store is closed")
+	ErrPhraseTaken        = errors.New("phrase already taken")
+	ErrInsufficientXP     = errors.New("insufficient xp")
+	ErrExtraDataType      = errors.New("unsupported extra data type")
+	ErrAwardTooSoon       = errors.New("xp award too soon after the last update")
+	ErrPlayerExists       = errors.New("player already exists")
+
+	// ErrVersionMismatch is returned by a save made with ExpectVersion when
+	// the player was changed since the expected version was read.
+	ErrVersionMismatch = errors.New("player changed since it was read")
+
+	// ErrExtraDataMarshal is returned when a player's extra data or flags
+	// can't be encoded as JSON, e.g., because T holds a channel or function,
+	// telling invalid save data apart from database failures.
+	ErrExtraDataMarshal = errors.New("cannot marshal player data")
+
+	// ErrTableNotInitialized is returned when a table the Store queries does
+	// not exist; run InitPlayerStateTable (or InitAuditTable) first.
+	ErrTableNotInitialized = errors.New("table not initialized")
+
+	// ErrSchemaMismatch is returned by VerifySchema when the player state
+	// table lacks a column the Store uses or holds one of the wrong type.
+	ErrSchemaMismatch = errors.New("table schema mismatch")
 )
 
 // PlayerState stores the data for each user.
 // The ExtraData field will be marshaled into a JSON string.
 // Make sure that the ExtraData field is a struct of the data you wish to add.
+// T may be a struct, a pointer to a struct (nil until data is loaded), a map
+// such as map[string]any, or any other JSON-decodable value type. Interfaces
+// with methods, channels, and functions can't be decoded into and make the
+// getters fail with ErrExtraDataType.
+// A nil pointer or map ExtraData is stored as the empty object, so players
+// read back always hold a non-nil pointer to a zero struct or an empty map.
+// A nil slice is stored as null and read back as nil.
 // The flags field is a map of indicators for system-level statuses
 // (e.g., "tutorial_completed": true, "is_premium": false)
+// DeletedAt is set only when the player has been soft-deleted.
+// UpdatedBy identifies who last changed the player, such as the player, an
+// admin, or a system job. Save, SaveExact, and ImportPlayer store it as given,
+// so set it before each write; it is empty when the writer didn't provide one.
+// Rank is the player's leaderboard position as of the last RefreshRanks, or
+// zero if they haven't been ranked yet; GetUserStatesWithRank sets it to the
+// current position instead.
 type PlayerState[T any] struct {
 	ExtraData   T               `json:"extra_data"`
 	XP          uint64          `json:"xp"`
@@ -32,286 +72,136 @@ type PlayerState[T any] struct {
 	UserName    string          `json:"user_name"`
 	Phrase      string          `json:"phrase"`
 	Flags       map[string]bool `json:"flags"`
+	DeletedAt   *time.Time      `json:"deleted_at,omitempty"`
+	UpdatedBy   string          `json:"updated_by,omitempty"`
+	Rank        uint64          `json:"rank,omitempty"`
 }
 
-// InitPlayerStateTable creates the player state table if it doesn't exist
-func InitPlayerStateTable(db *sql.DB, dbTableName string) error {
-	if db == nil {
-		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
-	}
-
-	query := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS %s (
-			id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
-			phrase VARCHAR(255) UNIQUE NOT NULL,
-			user_name VARCHAR(255) NOT NULL,
-			level INT4 NOT NULL DEFAULT 1,
-			xp INT8 NOT NULL DEFAULT 0,
-			last_updated TIMESTAMPTZ NOT NULL DEFAULT now(),
-			flags JSONB DEFAULT '{}',
-			extra_data JSONB DEFAULT '{}'
-		)
-	`, dbTableName)
-
-	_, err := db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("failed to create player state table: %w", err)
+// NewPlayerState returns a player ready to pass to Save, with a new UUID,
+// level 1, no XP, an empty Flags map, and the current time as LastUpdated,
+// mirroring the defaults InitPlayer applies.
+func NewPlayerState[T any](username, phrase string) *PlayerState[T] {
+	return &PlayerState[T]{
+		ID:          uuid.New(),
+		UserName:    username,
+		Phrase:      phrase,
+		Level:       1,
+		Flags:       make(map[string]bool),
+		LastUpdated: time.Now(),
 	}
-	return nil
 }
 
-// InitPlayer creates a new player in the database
-func InitPlayer(db *sql.DB, id uuid.UUID, username, phrase, dbTableName string) error {
-	if db == nil {
-		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+// Clone returns a copy of the player that can be modified without affecting
+// the original. Flags and DeletedAt are copied deeply; a nil Flags map stays
+// nil. ExtraData is copied by assignment, so a T that is or contains a
+// pointer, map, or slice still shares that data with the original.
+func (p *PlayerState[T]) Clone() *PlayerState[T] {
+	c := *p
+
+	if p.Flags != nil {
+		c.Flags = make(map[string]bool, len(p.Flags))
+		for key, value := range p.Flags {
+			c.Flags[key] = value
+		}
 	}
 
-	if id == uuid.Nil {
-		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	if p.DeletedAt != nil {
+		deletedAt := *p.DeletedAt
+		c.DeletedAt = &deletedAt
 	}
 
-	if username == "" || phrase == "" {
-		return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
-	}
+	return &c
+}
 
-	query := fmt.Sprintf(`
-		INSERT INTO %s (id, user_name, phrase)
-		VALUES ($1, $2, $3)
-		`, dbTableName)
+// InitPlayerStateTable creates the player state table if it doesn't exist
+func InitPlayerStateTable(db *sql.DB, dbTableName string) error {
+	return NewStore[any](db, dbTableName).InitPlayerStateTable()
+}
 
-	_, err := db.Exec(query, id, username, phrase)
-	if err != nil {
-		return fmt.Errorf("failed to create player: %w", err)
-	}
+// InitPlayer creates a new player in the database.
+// ErrPlayerExists is returned if the id or phrase is already registered.
+func InitPlayer(db *sql.DB, id uuid.UUID, username, phrase, dbTableName string) error {
+	return NewStore[any](db, dbTableName).InitPlayer(id, username, phrase)
+}
 
-	return nil
+// CreatePlayer creates a new player and returns their full state as stored,
+// in a single round trip.
+func CreatePlayer[T any](db *sql.DB, dbTableName, username, phrase string) (*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).CreatePlayer(username, phrase)
 }
 
 // GetUserStateByID takes the UUID for a player and returns a player state struct.
 func GetUserStateByID[T any](db *sql.DB, dbTableName string, id uuid.UUID) (*PlayerState[T], error) {
-	if db == nil {
-		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
-	}
-
-	if id == uuid.Nil {
-		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
-	}
-
-	var state PlayerState[T]
-	state.Flags = make(map[string]bool)
-
-	query := fmt.Sprintf(`
-		SELECT id, user_name, phrase, level, xp, last_updated, flags, extra_data
-		FROM %s
-		WHERE id = $1
-		`, dbTableName)
-
-	var flagsJSON, extraJSON []byte
-	err := db.QueryRow(query, id).Scan(
-		&state.ID,
-		&state.UserName,
-		&state.Phrase,
-		&state.Level,
-		&state.XP,
-		&state.LastUpdated,
-		&flagsJSON,
-		&extraJSON,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrPlayerNotFound
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to query player data: %w", err)
-	}
-
-	// Unmarshal the JSON fields
-	if err := json.Unmarshal(flagsJSON, &state.Flags); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal flags: %w", err)
-	}
-
-	if err := json.Unmarshal(extraJSON, &state.ExtraData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal extra data: %w", err)
-	}
-
-	return &state, nil
+	return NewStore[T](db, dbTableName).GetUserStateByID(id)
 }
 
 // GetUserStateByPhrase takes in the database table and user passphrase and
 // returns a PlayerState sturct.
 func GetUserStateByPhrase[T any](db *sql.DB, dbTableName, phrase string) (*PlayerState[T], error) {
-	if db == nil {
-		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
-	}
-
-	if phrase == "" {
-		return nil, fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
-	}
-
-	var state PlayerState[T]
-	state.Flags = make(map[string]bool)
-
-	query := fmt.Sprintf(`
-		SELECT id, user_name, phrase, level, xp, last_updated, flags, extra_data
-		FROM %s
-		WHERE phrase = $1
-		`, dbTableName)
-
-	var flagsJSON, extraJSON []byte
-	err := db.QueryRow(query, phrase).Scan(
-		&state.ID,
-		&state.UserName,
-		&state.Phrase,
-		&state.Level,
-		&state.XP,
-		&state.LastUpdated,
-		&flagsJSON,
-		&extraJSON,
-	)
-
-	if err == sql.ErrNoRows {
-		return nil, ErrPlayerNotFound
-	}
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to query player data by phrase: %w", err)
-	}
+	return NewStore[T](db, dbTableName).GetUserStateByPhrase(phrase)
+}
 
-	// Unmarshal the JSON fields
-	if err := json.Unmarshal(flagsJSON, &state.Flags); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal flags: %w", err)
-	}
+// PhraseExists reports whether a player with the given phrase is already stored.
+// It is cheaper than GetUserStateByPhrase since no row data is scanned or unmarshaled.
+func PhraseExists(db *sql.DB, dbTableName, phrase string) (bool, error) {
+	return NewStore[any](db, dbTableName).PhraseExists(phrase)
+}
 
-	if err := json.Unmarshal(extraJSON, &state.ExtraData); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal extra data: %w", err)
-	}
+// PlayerExists reports whether a player with the given UUID is already stored.
+func PlayerExists(db *sql.DB, dbTableName string, id uuid.UUID) (bool, error) {
+	return NewStore[any](db, dbTableName).PlayerExists(id)
+}
 
-	return &state, nil
+// GetXPAndLevel returns a player's XP and level without reading their flags
+// or extra data, for hot paths that need nothing else.
+func GetXPAndLevel(db *sql.DB, dbTableName string, id uuid.UUID) (xp uint64, level uint32, err error) {
+	return NewStore[any](db, dbTableName).GetXPAndLevel(id)
 }
 
 // Save takes the existing player and updates the DB with the new player information.
 // If the player does not exist; this function will initiate a DB entry with the provided
 // data and return.
 func (p *PlayerState[T]) Save(db *sql.DB, dbTableName string, xpIncrease uint64) error {
-	if db == nil {
-		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
-	}
-
-	if p.ID == uuid.Nil {
-		// Generate a new ID if needed
-		p.ID = uuid.New()
-	}
-
-	if p.UserName == "" || p.Phrase == "" {
-		return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
-	}
-
-	player, err := GetUserStateByID[T](db, dbTableName, p.ID)
-	if err != nil && !errors.Is(err, ErrPlayerNotFound) {
-		return fmt.Errorf("failed to fetch player state: %w", err)
-	}
-
-	if player == nil || errors.Is(err, ErrPlayerNotFound) {
-		log.Printf("Creating new player: %s\n", p.UserName)
-
-		// Initialize any nil fields
-		if p.Flags == nil {
-			p.Flags = make(map[string]bool)
-		}
-
-		// Set default values for new player
-		p.Level = 1
-		p.XP = xpIncrease
-		p.LastUpdated = time.Now()
-
-		// Create new player
-		err = InitPlayer(db, p.ID, p.UserName, p.Phrase, dbTableName)
-		if err != nil {
-			return fmt.Errorf("failed to initialize player: %w", err)
-		}
-
-		// If we just initialized with base values, we need to update with the complete state
-		extraData, err := json.Marshal(p.ExtraData)
-		if err != nil {
-			return fmt.Errorf("failed to marshal extra data: %w", err)
-		}
-
-		flags, err := json.Marshal(p.Flags)
-		if err != nil {
-			return fmt.Errorf("failed to marshal flags: %w", err)
-		}
-
-		query := fmt.Sprintf(`
-		UPDATE %s
-		SET level = $1,
-			xp = $2,
-			extra_data = $3,
-			flags = $4,
-			last_updated = $5
-		WHERE id = $6
-			`, dbTableName)
-
-		_, err = db.Exec(query,
-			p.Level,
-			p.XP,
-			extraData,
-			flags,
-			p.LastUpdated,
-			p.ID,
-		)
-
-		if err != nil {
-			return fmt.Errorf("failed to update new player data: %w", err)
-		}
-
-		return nil
-	}
-
-	// Update existing player
-	p.XP = player.XP + xpIncrease
-	p.LastUpdated = time.Now()
-
-	// Calculate level up
-	xpThreshold := (uint64(p.Level) * 200)
-	if p.XP >= xpThreshold && p.Level < player.Level+1 {
-		p.Level = player.Level + 1
-	}
+	return p.SaveWithOptions(db, dbTableName, AwardXP(xpIncrease))
+}
 
-	extraData, err := json.Marshal(p.ExtraData)
-	if err != nil {
-		return fmt.Errorf("failed to marshal extra data: %w", err)
-	}
+// SaveWithOptions saves the player as configured by opts, such as AwardXP and
+// WithoutTimestamp; with no options it is Save without an XP award.
+func (p *PlayerState[T]) SaveWithOptions(db *sql.DB, dbTableName string, opts ...SaveOption) error {
+	_, err := NewStore[T](db, dbTableName).SaveWithOptions(p, opts...)
+	return err
+}
 
-	flags, err := json.Marshal(p.Flags)
-	if err != nil {
-		return fmt.Errorf("failed to marshal flags: %w", err)
-	}
+// SaveDelta is like Save but takes a signed XP change. A negative delta
+// takes XP away, clamping at zero, and demotes the player to match.
+func (p *PlayerState[T]) SaveDelta(db *sql.DB, dbTableName string, delta int64) error {
+	_, err := NewStore[T](db, dbTableName).SaveDelta(p, delta)
+	return err
+}
 
-	query := fmt.Sprintf(`
-	UPDATE %s
-	SET level = $1,
-		xp = $2,
-		extra_data = $3,
-		flags = $4,
-		last_updated = $5
-	WHERE id = $6
-		`, dbTableName)
+// SaveContext is Save run on tx, typically an *sql.Tx, and bounded by ctx, so
+// the save joins the caller's transaction and sees its earlier writes.
+func (p *PlayerState[T]) SaveContext(ctx context.Context, tx Querier, dbTableName string, xpIncrease uint64) (SaveResult, error) {
+	return NewStore[T](nil, dbTableName).SaveContext(ctx, tx, p, xpIncrease)
+}
 
-	_, err = db.Exec(query,
-		p.Level,
-		p.XP,
-		extraData,
-		flags,
-		p.LastUpdated,
-		p.ID,
-	)
+// SaveQuiet is like Save but leaves an existing player's last update time
+// unchanged, for maintenance jobs whose writes shouldn't count as activity.
+func (p *PlayerState[T]) SaveQuiet(db *sql.DB, dbTableName string, xpIncrease uint64) error {
+	_, err := NewStore[T](db, dbTableName).SaveQuiet(p, xpIncrease)
+	return err
+}
 
-	if err != nil {
-		return fmt.Errorf("failed to update player data: %w", err)
-	}
+// PreviewSave computes the result Save would produce for the given XP award
+// against the player's current stored state, without writing anything.
+func (p *PlayerState[T]) PreviewSave(db *sql.DB, dbTableName string, xpIncrease uint64) (SaveResult, error) {
+	return NewStore[T](db, dbTableName).PreviewSave(p, xpIncrease)
+}
 
-	return nil
+// SaveExact writes the player's Level and XP exactly as given, along with its
+// flags and extra data, for admin and anti-cheat tooling.
+func (p *PlayerState[T]) SaveExact(db *sql.DB, dbTableName string) error {
+	return NewStore[T](db, dbTableName).SaveExact(p)
 }
 
 // Leader represents a player on the leaderboard
@@ -321,45 +211,14 @@ type Leader struct {
 	XP       uint64 `db:"xp"`
 }
 
-// GetLeaderboard fetches the top users by XP.
+// GetLeaderboard fetches the top users by XP. Players with equal XP are
+// ordered by level, then by who reached their XP first.
 func GetLeaderboard(db *sql.DB, dbTableName string, limit int) ([]Leader, error) {
-	if db == nil {
-		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
-	}
-
-	if limit <= 0 {
-		return nil, fmt.Errorf("%w: leaderboard limit must be greater than zero", ErrInvalidData)
-	}
-
-	query := fmt.Sprintf(`
-		SELECT user_name, level, xp
-		FROM %s
-		ORDER BY xp DESC
-		LIMIT $1`, dbTableName)
-
-	rows, err := db.Query(query, limit)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
-	}
-	defer rows.Close()
-
-	var users []Leader
-	for rows.Next() {
-		var user Leader
-		err := rows.Scan(
-			&user.UserName,
-			&user.Level,
-			&user.XP,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan leaderboard row: %w", err)
-		}
-		users = append(users, user)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating through leaderboard rows: %w", err)
-	}
+	return NewStore[any](db, dbTableName).GetLeaderboard(limit)
+}
 
-	return users, nil
+// QueryLeaderboardRows runs the leaderboard query and returns its rows for
+// custom scanning. The caller must Close the rows.
+func QueryLeaderboardRows(ctx context.Context, db *sql.DB, dbTableName string, limit int) (*sql.Rows, error) {
+	return NewStore[any](db, dbTableName).QueryLeaderboardRows(ctx, limit)
 }
@@ -0,0 +1,44 @@
+package ghostplay
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCloneIsIndependent(t *testing.T) {
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	p := NewPlayerState[any]("player", "phrase")
+	p.Flags["premium"] = true
+	p.DeletedAt = &deletedAt
+
+	c := p.Clone()
+	c.Flags["premium"] = false
+	c.Flags["beta"] = true
+	*c.DeletedAt = deletedAt.Add(time.Hour)
+	c.XP = 100
+
+	if !p.Flags["premium"] || len(p.Flags) != 1 {
+		t.Errorf("original flags = %v, want only premium set", p.Flags)
+	}
+
+	if !p.DeletedAt.Equal(deletedAt) {
+		t.Errorf("original DeletedAt = %v, want %v", p.DeletedAt, deletedAt)
+	}
+
+	if p.XP != 0 {
+		t.Errorf("original XP = %d, want 0", p.XP)
+	}
+}
+
+func TestCloneKeepsNil(t *testing.T) {
+	p := &PlayerState[any]{}
+
+	c := p.Clone()
+	if c.Flags != nil {
+		t.Errorf("Clone().Flags = %v, want nil", c.Flags)
+	}
+
+	if c.DeletedAt != nil {
+		t.Errorf("Clone().DeletedAt = %v, want nil", c.DeletedAt)
+	}
+}
@@ -0,0 +1,142 @@
+package ghostplay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TableName is a table name that has been validated and quoted once by
+// NewTableName, so it can be passed around without being checked again.
+// The zero TableName is invalid.
+type TableName struct {
+	name   string
+	quoted string
+}
+
+// NewTableName validates a possibly schema-qualified table name, such as
+// "game.player_state", following the same rules as NewStore. ErrInvalidData
+// is returned if the name isn't a valid identifier.
+func NewTableName(name string) (TableName, error) {
+	quoted, err := quoteTableName(name)
+	if err != nil {
+		return TableName{}, err
+	}
+	return TableName{name: name, quoted: quoted}, nil
+}
+
+// String returns the table name as it was given to NewTableName.
+func (t TableName) String() string {
+	return t.name
+}
+
+// Quoted returns the table name quoted for use in SQL, e.g., in custom
+// queries alongside QueryLeaderboardRows.
+func (t TableName) Quoted() string {
+	return t.quoted
+}
+
+// maxIdentifierParts allows names qualified as far as database.schema.table.
+const maxIdentifierParts = 3
+
+// quoteTableName validates a possibly schema-qualified table name, such as
+// "game.player_state", and quotes each part so it is safe to place in SQL.
+// Unquoted parts must be plain identifiers and are lowercased, as Postgres
+// folds them; parts already in double quotes are kept as written.
+func quoteTableName(name string) (string, error) {
+	parts, err := splitTableName(name)
+	if err != nil {
+		return "", err
+	}
+
+	quoted := make([]string, len(parts))
+	for i, part := range parts {
+		quoted[i] = quoteIdentifier(part)
+	}
+	return strings.Join(quoted, "."), nil
+}
+
+// columnName validates a column name and returns it as Postgres stores it,
+// following the rules quoteTableName applies to each part of a table name:
+// an unquoted name must be a plain identifier and is lowercased, while one in
+// double quotes is kept as written.
+func columnName(name string) (string, error) {
+	parts, err := splitTableName(name)
+	if err != nil || len(parts) != 1 {
+		return "", fmt.Errorf("%w: invalid column name %q", ErrInvalidData, name)
+	}
+	return parts[0], nil
+}
+
+// splitTableName splits a table name into its unquoted identifier parts.
+func splitTableName(name string) ([]string, error) {
+	invalid := fmt.Errorf("%w: invalid table name %q", ErrInvalidData, name)
+
+	var parts []string
+	for rest := name; ; {
+		var part string
+		if strings.HasPrefix(rest, `"`) {
+			// Find the closing quote, skipping doubled quotes
+			end := 1
+			for {
+				i := strings.IndexByte(rest[end:], '"')
+				if i < 0 {
+					return nil, invalid
+				}
+				end += i + 1
+				if !strings.HasPrefix(rest[end:], `"`) {
+					break
+				}
+				end++
+			}
+			part = strings.ReplaceAll(rest[1:end-1], `""`, `"`)
+			rest = rest[end:]
+		} else {
+			end := strings.IndexByte(rest, '.')
+			if end < 0 {
+				end = len(rest)
+			}
+			part = rest[:end]
+			if !isPlainIdentifier(part) {
+				return nil, invalid
+			}
+			part = strings.ToLower(part)
+			rest = rest[end:]
+		}
+
+		if part == "" {
+			return nil, invalid
+		}
+		parts = append(parts, part)
+
+		if rest == "" {
+			break
+		}
+		if rest[0] != '.' || len(parts) == maxIdentifierParts {
+			return nil, invalid
+		}
+		rest = rest[1:]
+	}
+	return parts, nil
+}
+
+// isPlainIdentifier reports whether s is a valid unquoted SQL identifier.
+func isPlainIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, r := range s {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r == '$' || r >= '0' && r <= '9'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// quoteIdentifier quotes a single identifier, escaping embedded quotes.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,76 @@
+package ghostplay
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestQuoteTableName(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"player_state", `"player_state"`},
+		{"Player_State", `"player_state"`},
+		{"public.player_state", `"public"."player_state"`},
+		{"game.player_state", `"game"."player_state"`},
+		{"GameData.Players", `"gamedata"."players"`},
+		{`"GameData"."Players"`, `"GameData"."Players"`},
+		{`"GameData".players`, `"GameData"."players"`},
+		{`"odd.name"`, `"odd.name"`},
+		{`"say ""hi"""`, `"say ""hi"""`},
+		{"db.game.player_state", `"db"."game"."player_state"`},
+		{"players$2", `"players$2"`},
+	}
+
+	for _, tt := range tests {
+		got, err := quoteTableName(tt.name)
+		if err != nil {
+			t.Errorf("quoteTableName(%q) = %v", tt.name, err)
+			continue
+		}
+
+		if got != tt.want {
+			t.Errorf("quoteTableName(%q) = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestQuoteTableNameInvalid(t *testing.T) {
+	names := []string{
+		"",
+		".",
+		"game.",
+		".players",
+		"game..players",
+		"a.b.c.d",
+		"player state",
+		"players; DROP TABLE players",
+		"1players",
+		"$players",
+		`"unterminated`,
+		`""`,
+		`"game"players`,
+	}
+
+	for _, name := range names {
+		if _, err := quoteTableName(name); !errors.Is(err, ErrInvalidData) {
+			t.Errorf("quoteTableName(%q) = %v, want ErrInvalidData", name, err)
+		}
+	}
+}
+
+func TestNewTableName(t *testing.T) {
+	table, err := NewTableName("Game.Players")
+	if err != nil {
+		t.Fatalf("NewTableName() = %v", err)
+	}
+
+	if table.String() != "Game.Players" {
+		t.Errorf("String() = %s, want Game.Players", table.String())
+	}
+
+	if table.Quoted() != `"game"."players"` {
+		t.Errorf("Quoted() = %s, want \"game\".\"players\"", table.Quoted())
+	}
+}
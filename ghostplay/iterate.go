@@ -0,0 +1,115 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IteratePlayers returns the next batch of up to batchSize players with IDs
+// greater than afterID, in ID order, along with the cursor for the next call.
+func IteratePlayers[T any](ctx context.Context, db *sql.DB, dbTableName string, afterID uuid.UUID, batchSize int) ([]PlayerState[T], uuid.UUID, error) {
+	return NewStore[T](db, dbTableName).IteratePlayers(ctx, afterID, batchSize)
+}
+
+// IteratePlayers returns the next batch of up to batchSize players with IDs
+// greater than afterID, in ID order, along with the cursor for the next call.
+// Pass uuid.Nil to start from the beginning of the table.
+//
+// Pages are found by keyset on the primary key rather than OFFSET, so each
+// call costs the same however deep into the table it is. The returned cursor
+// is uuid.Nil once the final batch has been read, which may be a short or an
+// empty batch:
+//
+//	cursor := uuid.Nil
+//	for {
+//		batch, next, err := store.IteratePlayers(ctx, cursor, 500)
+//		if err != nil {
+//			return err
+//		}
+//		// process batch
+//		if next == uuid.Nil {
+//			break
+//		}
+//		cursor = next
+//	}
+func (s *Store[T]) IteratePlayers(ctx context.Context, afterID uuid.UUID, batchSize int) ([]PlayerState[T], uuid.UUID, error) {
+	if s.db == nil {
+		return nil, uuid.Nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("batch size", batchSize); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {id} > $1 AND {live}
+		ORDER BY {id}
+		LIMIT $2`)
+
+	var players []*PlayerState[T]
+	err := s.doContext(ctx, "iterate", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(afterID, batchSize)...)
+		return err
+	})
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	var next uuid.UUID
+	if len(players) == batchSize {
+		next = players[len(players)-1].ID
+	}
+
+	return derefPlayers(players), next, nil
+}
+
+// GetPlayersUpdatedSince returns up to limit players last updated after
+// since, oldest update first.
+func GetPlayersUpdatedSince[T any](db *sql.DB, dbTableName string, since time.Time, limit int) ([]PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetPlayersUpdatedSince(since, limit)
+}
+
+// GetPlayersUpdatedSince returns up to limit players last updated after
+// since, oldest update first, for incremental syncs. Page through changes by
+// passing the LastUpdated of the final player returned as the next since.
+//
+// Players sharing that exact timestamp beyond the limit would be skipped by
+// the next page, so use a limit comfortably larger than the number of players
+// updated at any one instant. Soft-deleting a player doesn't change
+// LastUpdated, and soft-deleted players are returned only when the Store
+// includes deleted players.
+func (s *Store[T]) GetPlayersUpdatedSince(since time.Time, limit int) ([]PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("limit", limit); err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {last_updated} > $1 AND {live}
+		ORDER BY {last_updated} ASC, {id} ASC
+		LIMIT $2`)
+
+	var players []*PlayerState[T]
+	err := s.do("updated_since", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(since, limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return derefPlayers(players), nil
+}
@@ -0,0 +1,411 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Tiebreaker decides the order of players with equal XP on a leaderboard.
+// Every tiebreaker ends with the player id so the order is stable across
+// calls and pages.
+type Tiebreaker int
+
+const (
+	// TiebreakLevelThenOldest ranks higher levels first, then the player
+	// who reached their XP earliest. It is the default.
+	TiebreakLevelThenOldest Tiebreaker = iota
+
+	// TiebreakID orders tied players by id only.
+	TiebreakID
+)
+
+// orderBy returns the ORDER BY terms that follow the primary sort key.
+func (t Tiebreaker) orderBy() (string, error) {
+	switch t {
+	case TiebreakLevelThenOldest:
+		return "{level} DESC, {last_updated} ASC, {id} ASC", nil
+	case TiebreakID:
+		return "{id} ASC", nil
+	}
+	return "", fmt.Errorf("%w: unknown leaderboard tiebreaker %d", ErrInvalidData, t)
+}
+
+// GetLeaderboardWithTiebreaker fetches the top users by XP, ordering players
+// with equal XP by the given tiebreaker.
+func GetLeaderboardWithTiebreaker(db *sql.DB, dbTableName string, limit int, tiebreaker Tiebreaker) ([]Leader, error) {
+	s := NewStore[any](db, dbTableName)
+	s.Tiebreaker = tiebreaker
+	return s.GetLeaderboard(limit)
+}
+
+// GetLeaderboardByLevelRange fetches the top users by XP among players whose
+// level is between minLevel and maxLevel, inclusive.
+func GetLeaderboardByLevelRange(db *sql.DB, dbTableName string, minLevel, maxLevel uint32, limit int) ([]Leader, error) {
+	return NewStore[any](db, dbTableName).GetLeaderboardByLevelRange(minLevel, maxLevel, limit)
+}
+
+// GetLeaderboardByLevelRange fetches the top users by XP among players whose
+// level is between minLevel and maxLevel, inclusive. Players with equal XP are
+// ordered by the Store's Tiebreaker.
+func (s *Store[T]) GetLeaderboardByLevelRange(minLevel, maxLevel uint32, limit int) ([]Leader, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if minLevel > maxLevel {
+		return nil, fmt.Errorf("%w: min level %d is greater than max level %d", ErrInvalidData, minLevel, maxLevel)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {user_name}, {level}, {xp}
+		FROM {table}
+		WHERE {level} BETWEEN $1 AND $2 AND {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $3`)
+
+	var users []Leader
+	err = s.do("leaderboard_by_level_range", func(ctx context.Context) error {
+		var err error
+		users, err = queryLeaders(ctx, s.reader(), query, s.seasonArgs(minLevel, maxLevel, limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetTopPlayer returns the full state of the player with the most XP.
+func GetTopPlayer[T any](db *sql.DB, dbTableName string) (*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetTopPlayer()
+}
+
+// GetTopPlayer returns the full state of the player with the most XP, the
+// player GetLeaderboard would rank first; ties are broken by the Store's
+// Tiebreaker. ErrPlayerNotFound is returned when the table has no players.
+func (s *Store[T]) GetTopPlayer() (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT 1`)
+
+	var state *PlayerState[T]
+	err = s.do("top_player", func(ctx context.Context) error {
+		var err error
+		state, err = s.scanPlayer(s.reader().QueryRowContext(ctx, query, s.seasonArgs()...))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query top player: %w", err)
+	}
+
+	return state, nil
+}
+
+// GetPlayerAtRank returns the full state of the player at the given
+// 1-based position on the leaderboard.
+func GetPlayerAtRank[T any](db *sql.DB, dbTableName string, rank int) (*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetPlayerAtRank(rank)
+}
+
+// GetPlayerAtRank returns the full state of the player at the given 1-based
+// position on the leaderboard, the player GetLeaderboard would list at that
+// position; rank 1 is the player GetTopPlayer returns. Ties are broken by the
+// Store's Tiebreaker, so each position holds exactly one player, unlike the
+// shared ranks of GetLeaderboardWithRank.
+// ErrPlayerNotFound is returned when rank exceeds the number of players.
+func (s *Store[T]) GetPlayerAtRank(rank int) (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if rank < 1 {
+		return nil, fmt.Errorf("%w: rank must be at least 1", ErrInvalidData)
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		OFFSET $1
+		LIMIT 1`)
+
+	var state *PlayerState[T]
+	err = s.do("player_at_rank", func(ctx context.Context) error {
+		var err error
+		state, err = s.scanPlayer(s.reader().QueryRowContext(ctx, query, s.seasonArgs(rank-1)...))
+		return err
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player at rank %d: %w", rank, err)
+	}
+
+	return state, nil
+}
+
+// GetLeaderboardByLevel fetches the top users by level, using XP to order
+// players of the same level.
+func GetLeaderboardByLevel(db *sql.DB, dbTableName string, limit int) ([]Leader, error) {
+	return NewStore[any](db, dbTableName).GetLeaderboardByLevel(limit)
+}
+
+// GetLeaderboardByLevel fetches the top users by level, using XP to order
+// players of the same level and then who reached their XP first.
+// The query is served by the (level, xp) index InitPlayerStateTable creates.
+func (s *Store[T]) GetLeaderboardByLevel(limit int) ([]Leader, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {user_name}, {level}, {xp}
+		FROM {table}
+		WHERE {live}
+		ORDER BY {level} DESC, {xp} DESC, {last_updated} ASC, {id} ASC
+		LIMIT $1`)
+
+	var users []Leader
+	err := s.do("leaderboard_by_level", func(ctx context.Context) error {
+		var err error
+		users, err = queryLeaders(ctx, s.reader(), query, s.seasonArgs(limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// GetPlayersByXPRange returns up to limit players whose XP is between minXP
+// and maxXP, inclusive.
+func GetPlayersByXPRange[T any](db *sql.DB, dbTableName string, minXP, maxXP uint64, limit int) ([]PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetPlayersByXPRange(minXP, maxXP, limit)
+}
+
+// GetPlayersByXPRange returns up to limit players whose XP is between minXP
+// and maxXP, inclusive, ordered by XP, highest first. An empty slice is
+// returned when nobody falls in the range.
+func (s *Store[T]) GetPlayersByXPRange(minXP, maxXP uint64, limit int) ([]PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if minXP > maxXP {
+		return nil, fmt.Errorf("%w: min xp %d is greater than max xp %d", ErrInvalidData, minXP, maxXP)
+	}
+
+	if err := checkLimit("limit", limit); err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {xp} BETWEEN $1 AND $2 AND {live}
+		ORDER BY {xp} DESC, {id}
+		LIMIT $3`)
+
+	var players []*PlayerState[T]
+	err := s.do("get_by_xp_range", func(ctx context.Context) error {
+		var err error
+		players, err = s.queryPlayers(ctx, s.reader(), query, s.seasonArgs(min(minXP, math.MaxInt64), min(maxXP, math.MaxInt64), limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return derefPlayers(players), nil
+}
+
+// GetMultiTableLeaderboard fetches the top users by XP across several player
+// state tables, such as one per region, as a single global leaderboard.
+// Players with equal XP are ordered by level, then by who reached their XP
+// first. Every table name is validated, and ErrInvalidData is returned if
+// tableNames is empty.
+//
+// Each table contributes its own top limit players and the combined rows are
+// sorted again, all in one UNION ALL query, so at most len(tableNames) * limit
+// rows are merged. Every table is still scanned and sorted for its top
+// players, so the cost grows linearly with the number of tables; the query
+// also grows with each table, so prefer tens of tables over thousands.
+func GetMultiTableLeaderboard(db *sql.DB, tableNames []string, limit int) ([]Leader, error) {
+	if db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if len(tableNames) == 0 {
+		return nil, fmt.Errorf("%w: at least one table name is required", ErrInvalidData)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	tiebreak, err := TiebreakLevelThenOldest.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	// Alias the columns so the outer query can sort rows from every table
+	selects := make([]string, len(tableNames))
+	for i, name := range tableNames {
+		s := NewStore[any](db, name)
+		if s.tableErr != nil {
+			return nil, s.tableErr
+		}
+
+		selects[i] = s.expand(`
+		(SELECT {user_name} AS user_name, {level} AS level, {xp} AS xp,
+			{last_updated} AS last_updated, {id} AS id
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $1)`)
+	}
+
+	query := `
+		SELECT user_name, level, xp
+		FROM (` + strings.Join(selects, `
+		UNION ALL`) + `
+		) AS shards
+		ORDER BY xp DESC, level DESC, last_updated ASC, id ASC
+		LIMIT $1`
+
+	s := NewStore[any](db, tableNames[0])
+	var users []Leader
+	err = s.do("multi_table_leaderboard", func(ctx context.Context) error {
+		var err error
+		users, err = queryLeaders(ctx, db, query, s.seasonArgs(limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// RankedLeader is a leaderboard entry with its rank, starting at 1.
+type RankedLeader struct {
+	Rank int
+	Leader
+}
+
+// GetLeaderboardWithRank fetches a page of the leaderboard by XP, starting
+// offset entries in, with each entry's rank.
+func GetLeaderboardWithRank(db *sql.DB, dbTableName string, limit, offset int) ([]RankedLeader, error) {
+	return NewStore[any](db, dbTableName).GetLeaderboardWithRank(limit, offset)
+}
+
+// GetLeaderboardWithRank fetches a page of the leaderboard by XP, starting
+// offset entries in, with each entry's rank. Ranks are computed over the
+// whole leaderboard, so they continue across pages.
+//
+// Players with equal XP share a rank and the ranks after them are skipped
+// (1, 2, 2, 4), as with SQL's RANK(); tied players are listed in the order
+// of the Store's Tiebreaker, matching GetLeaderboard.
+func (s *Store[T]) GetLeaderboardWithRank(limit, offset int) ([]RankedLeader, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	if offset < 0 {
+		return nil, fmt.Errorf("%w: leaderboard offset cannot be negative", ErrInvalidData)
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	// The window runs before LIMIT and OFFSET, so ranks span every page
+	query := s.expand(`
+		SELECT {user_name}, {level}, {xp}, RANK() OVER (ORDER BY {xp} DESC)
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $1 OFFSET $2`)
+
+	var leaders []RankedLeader
+	err = s.do("leaderboard_with_rank", func(ctx context.Context) error {
+		rows, err := s.reader().QueryContext(ctx, query, s.seasonArgs(limit, offset)...)
+		if err != nil {
+			return fmt.Errorf("failed to query leaderboard: %w", err)
+		}
+		defer rows.Close()
+
+		leaders = nil
+		for rows.Next() {
+			var leader RankedLeader
+			err := rows.Scan(
+				&leader.UserName,
+				&leader.Level,
+				&leader.XP,
+				&leader.Rank,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to scan leaderboard row: %w", err)
+			}
+			leaders = append(leaders, leader)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through leaderboard rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return leaders, nil
+}
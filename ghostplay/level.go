@@ -0,0 +1,322 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// LevelCurve returns the total XP a player needs to reach the given level.
+// Requirements are cumulative: they are compared against a player's total
+// XP, not the XP earned since their last level-up. A player is at the
+// highest level whose requirement their total XP meets. Level 1 requires
+// 0 XP and requirements must increase with every level.
+type LevelCurve func(level uint32) uint64
+
+// xpPerLevel is the XP step between levels on the default curve.
+const xpPerLevel = 200
+
+// DefaultLevelCurve requires 200 XP per level: level n is reached at a total
+// of (n-1) * 200 XP. A player is level 1 from 0 to 199 XP, level 2 from 200
+// to 399 XP, and level n from (n-1) * 200 to n*200 - 1 XP inclusive.
+func DefaultLevelCurve(level uint32) uint64 {
+	if level <= 1 {
+		return 0
+	}
+	return uint64(level-1) * xpPerLevel
+}
+
+// maxCurveLevels bounds how many level thresholds are sent to the database
+// when levels are computed server-side; the highest level computed there.
+const maxCurveLevels = 100_000
+
+// maxCurveLevel is maxCurveLevels formatted for use in SQL.
+var maxCurveLevel = strconv.Itoa(maxCurveLevels)
+
+// validatedCurveLevels is how many levels Validate checks.
+const validatedCurveLevels = 1000
+
+// Validate reports whether the curve requires 0 XP for level 1 and strictly
+// more XP for each level than the one before, checked over the first 1000
+// levels. It returns ErrInvalidData naming the first level that breaks the
+// rule.
+func (c LevelCurve) Validate() error {
+	if c == nil {
+		return fmt.Errorf("%w: level curve is nil", ErrInvalidData)
+	}
+
+	if xp := c(1); xp != 0 {
+		return fmt.Errorf("%w: level curve requires %d xp for level 1, want 0", ErrInvalidData, xp)
+	}
+
+	prev := uint64(0)
+	for level := uint32(2); level <= validatedCurveLevels; level++ {
+		xp := c(level)
+		if xp <= prev {
+			return fmt.Errorf("%w: level curve requires %d xp for level %d, not more than %d for level %d",
+				ErrInvalidData, xp, level, prev, level-1)
+		}
+		prev = xp
+	}
+	return nil
+}
+
+// checkCurve validates the Store's LevelCurve the first time it is called.
+func (s *Store[T]) checkCurve() error {
+	if s.LevelCurve == nil {
+		return nil
+	}
+
+	s.curveOnce.Do(func() {
+		s.curveErr = s.LevelCurve.Validate()
+	})
+	return s.curveErr
+}
+
+// curve returns the Store's LevelCurve, falling back to DefaultLevelCurve.
+func (s *Store[T]) curve() LevelCurve {
+	if s.LevelCurve != nil {
+		return s.LevelCurve
+	}
+	return DefaultLevelCurve
+}
+
+// levelForXP returns the highest level whose requirement is met by xp.
+func (c LevelCurve) levelForXP(xp uint64) uint32 {
+	// Double an upper bound until it is out of reach, then binary search
+	lo, hi := uint32(1), uint32(2)
+	for c(hi) <= xp {
+		lo = hi
+		if hi > math.MaxUint32/2 {
+			hi = math.MaxUint32
+			if c(hi) <= xp {
+				return hi
+			}
+			break
+		}
+		hi *= 2
+	}
+
+	for hi-lo > 1 {
+		mid := lo + (hi-lo)/2
+		if c(mid) <= xp {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// thresholds formats the XP requirements for levels 2 and up, as far as
+// needed to place maxXP, as a Postgres int8[] literal. Combined with
+// width_bucket, 1 + width_bucket(xp, thresholds) is the level for xp.
+// Requirements stop at maxCurveLevels, so XP beyond that level's requirement
+// is placed at maxCurveLevels; callers keep a stored level above it.
+func (c LevelCurve) thresholds(maxXP uint64) string {
+	top := min(c.levelForXP(maxXP), maxCurveLevels-1) + 1
+
+	// Include the first unreached level so the array is never empty
+	parts := make([]string, 0, top)
+	for level := uint32(2); level <= top; level++ {
+		parts = append(parts, strconv.FormatUint(min(c(level), math.MaxInt64), 10))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// RecalculateAllLevels recomputes every player's level from their XP using
+// curve, e.g., after changing the progression formula.
+func RecalculateAllLevels(db *sql.DB, dbTableName string, curve LevelCurve) (int64, error) {
+	s := NewStore[any](db, dbTableName)
+	s.LevelCurve = curve
+	return s.RecalculateAllLevels()
+}
+
+// RecalculateAllLevels recomputes every player's level from their XP using the
+// Store's LevelCurve, never below StartingLevel, and returns the number of
+// players whose level changed.
+//
+// The update runs as one statement that rewrites only the rows that change,
+// but on very large tables it still holds many row locks; prefer running it
+// while XP awards are quiet. Players awarded XP beyond the table's previous
+// maximum while it runs are capped one level above that maximum. Levels are
+// computed up to 100,000; players with the XP for that level are raised to
+// it but keep any higher level they hold.
+func (s *Store[T]) RecalculateAllLevels() (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	maxQuery := s.expand(`
+		SELECT COALESCE(MAX({xp}), 0)
+		FROM {table}
+		WHERE {season}
+		`)
+
+	level := `CASE
+			WHEN 1 + width_bucket({xp}, $2::int8[]) >= ` + maxCurveLevel + `
+			THEN GREATEST({level}, ` + maxCurveLevel + `)
+			ELSE GREATEST($1, 1 + width_bucket({xp}, $2::int8[]))
+		END`
+
+	update := s.expand(`
+		UPDATE {table}
+		SET {level} = ` + level + `
+		WHERE {level} <> ` + level + ` AND {season}
+		`)
+
+	var affected int64
+	err := s.do("recalculate_levels", func(ctx context.Context) error {
+		var maxXP uint64
+		if err := s.db.QueryRowContext(ctx, maxQuery, s.seasonArgs()...).Scan(&maxXP); err != nil {
+			return fmt.Errorf("failed to query max xp: %w", err)
+		}
+
+		thresholds := s.curve().thresholds(maxXP)
+		result, err := s.db.ExecContext(ctx, update, s.seasonArgs(s.StartingLevel, thresholds)...)
+		if err != nil {
+			return fmt.Errorf("failed to recalculate levels: %w", err)
+		}
+
+		affected, err = result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("failed to read affected rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.afterBulkSave(affected)
+	return affected, nil
+}
+
+// CountWouldLevelUp returns how many players a flat grant of xpGrant XP would
+// level up.
+func CountWouldLevelUp(db *sql.DB, dbTableName string, xpGrant uint64) (int64, error) {
+	return NewStore[any](db, dbTableName).CountWouldLevelUp(xpGrant)
+}
+
+// CountWouldLevelUp returns how many players a flat grant of xpGrant XP would
+// level up, without writing anything. A player counts when their XP plus the
+// grant meets the Store's LevelCurve requirement for the level after their
+// current one; the count is computed server-side in one pass over the table.
+// It assumes the grant would be made with AutoLevel enabled. Players at
+// level 100,000 or above aren't counted.
+func (s *Store[T]) CountWouldLevelUp(xpGrant uint64) (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if xpGrant > math.MaxInt64 {
+		return 0, fmt.Errorf("%w: xp grant is too large", ErrInvalidData)
+	}
+
+	maxQuery := s.expand(`
+		SELECT COALESCE(MAX({xp}), 0)
+		FROM {table}
+		WHERE {live}
+		`)
+
+	// Element n of the thresholds is the requirement for level n+1
+	count := s.expand(`
+		SELECT COUNT(*)
+		FROM {table}
+		WHERE {live} AND {xp}::numeric + $1 >= ($2::int8[])[{level}]
+		`)
+
+	var players int64
+	err := s.do("count_would_level_up", func(ctx context.Context) error {
+		var maxXP uint64
+		if err := s.reader().QueryRowContext(ctx, maxQuery, s.seasonArgs()...).Scan(&maxXP); err != nil {
+			return fmt.Errorf("failed to query max xp: %w", err)
+		}
+
+		thresholds := s.curve().thresholds(maxXP + min(xpGrant, math.MaxUint64-maxXP))
+		if err := s.reader().QueryRowContext(ctx, count, s.seasonArgs(int64(xpGrant), thresholds)...).Scan(&players); err != nil {
+			return fmt.Errorf("failed to count level-ups: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return players, nil
+}
+
+// NextLevelXP returns the total XP at which the player reaches their next
+// level on DefaultLevelCurve. Use Store.NextLevelXP for a Store with its own
+// curve.
+func (p *PlayerState[T]) NextLevelXP() uint64 {
+	return DefaultLevelCurve(p.Level + 1)
+}
+
+// XPIntoCurrentLevel returns how much XP the player has earned since reaching
+// their current level on DefaultLevelCurve. It is zero for players holding
+// less XP than their level normally requires, such as those created with a
+// custom starting level. Use Store.XPIntoCurrentLevel for a Store with its
+// own curve.
+func (p *PlayerState[T]) XPIntoCurrentLevel() uint64 {
+	return xpIntoLevel(DefaultLevelCurve, p.XP, p.Level)
+}
+
+// NextLevelXP returns the total XP at which p reaches their next level on the
+// Store's LevelCurve, the threshold Save levels them up at.
+func (s *Store[T]) NextLevelXP(p *PlayerState[T]) uint64 {
+	return s.curve()(p.Level + 1)
+}
+
+// XPIntoCurrentLevel returns how much XP p has earned since reaching their
+// current level on the Store's LevelCurve, or zero if they hold less XP than
+// the level requires.
+func (s *Store[T]) XPIntoCurrentLevel(p *PlayerState[T]) uint64 {
+	return xpIntoLevel(s.curve(), p.XP, p.Level)
+}
+
+// xpIntoLevel returns how far xp is past the requirement for level on curve.
+func xpIntoLevel(curve LevelCurve, xp uint64, level uint32) uint64 {
+	levelStart := curve(level)
+	if xp < levelStart {
+		return 0
+	}
+	return xp - levelStart
+}
+
+// XPForLevel returns the total XP needed to reach level on DefaultLevelCurve.
+// Use Store.XPForLevel for a Store with its own curve.
+func XPForLevel(level uint32) uint64 {
+	return DefaultLevelCurve(level)
+}
+
+// XPForLevel returns the total XP needed to reach level on the Store's
+// LevelCurve, the threshold Save compares a player's total XP against.
+func (s *Store[T]) XPForLevel(level uint32) uint64 {
+	return s.curve()(level)
+}
+
+// ProgressToLevel returns how much more XP current needs to reach target on
+// DefaultLevelCurve, or zero if their XP already meets it.
+// Use Store.ProgressToLevel for a Store with its own curve.
+func ProgressToLevel[T any](current *PlayerState[T], target uint32) uint64 {
+	return remainingXP(DefaultLevelCurve, current.XP, target)
+}
+
+// ProgressToLevel returns how much more XP p needs to reach target on the
+// Store's LevelCurve, or zero if their XP already meets it.
+func (s *Store[T]) ProgressToLevel(p *PlayerState[T], target uint32) uint64 {
+	return remainingXP(s.curve(), p.XP, target)
+}
+
+// remainingXP returns the XP still needed to go from xp to target on curve.
+func remainingXP(curve LevelCurve, xp uint64, target uint32) uint64 {
+	required := curve(target)
+	if xp >= required {
+		return 0
+	}
+	return required - xp
+}
@@ -0,0 +1,253 @@
+package ghostplay
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestLevelForXPDefaultCurve(t *testing.T) {
+	tests := []struct {
+		xp   uint64
+		want uint32
+	}{
+		{0, 1},
+		{1, 1},
+		{199, 1},
+		{200, 2},
+		{201, 2},
+		{399, 2},
+		{400, 3},
+		{19_999, 100},
+		{20_000, 101},
+		{math.MaxUint64, math.MaxUint32},
+	}
+
+	for _, tt := range tests {
+		if got := LevelCurve(DefaultLevelCurve).levelForXP(tt.xp); got != tt.want {
+			t.Errorf("levelForXP(%d) = %d, want %d", tt.xp, got, tt.want)
+		}
+	}
+}
+
+func TestLevelForXPCustomCurve(t *testing.T) {
+	// 0, 100, 400, 900, ... XP for levels 1, 2, 3, 4, ...
+	square := LevelCurve(func(level uint32) uint64 {
+		n := uint64(level - 1)
+		return n * n * 100
+	})
+
+	tests := []struct {
+		xp   uint64
+		want uint32
+	}{
+		{0, 1},
+		{99, 1},
+		{100, 2},
+		{399, 2},
+		{400, 3},
+		{899, 3},
+		{900, 4},
+	}
+
+	for _, tt := range tests {
+		if got := square.levelForXP(tt.xp); got != tt.want {
+			t.Errorf("levelForXP(%d) = %d, want %d", tt.xp, got, tt.want)
+		}
+	}
+}
+
+func TestLevelForXPMatchesCurve(t *testing.T) {
+	curve := LevelCurve(DefaultLevelCurve)
+	for level := uint32(1); level <= 1000; level++ {
+		xp := curve(level)
+		if got := curve.levelForXP(xp); got != level {
+			t.Errorf("levelForXP(%d) = %d, want %d", xp, got, level)
+		}
+
+		if level > 1 {
+			if got := curve.levelForXP(xp - 1); got != level-1 {
+				t.Errorf("levelForXP(%d) = %d, want %d", xp-1, got, level-1)
+			}
+		}
+	}
+}
+
+func TestLevelCurveValidate(t *testing.T) {
+	tests := []struct {
+		name  string
+		curve LevelCurve
+		valid bool
+	}{
+		{"default", DefaultLevelCurve, true},
+		{"nil", nil, false},
+		{"zero", func(uint32) uint64 { return 0 }, false},
+		{"level 1 above zero", func(level uint32) uint64 { return uint64(level) * 100 }, false},
+		{"flat", func(level uint32) uint64 {
+			if level <= 1 {
+				return 0
+			}
+			return 100
+		}, false},
+		{"decreasing", func(level uint32) uint64 {
+			if level <= 1 {
+				return 0
+			}
+			if level == 50 {
+				return 1
+			}
+			return uint64(level) * 100
+		}, false},
+	}
+
+	for _, tt := range tests {
+		err := tt.curve.Validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: Validate() = %v, want nil", tt.name, err)
+		}
+
+		if !tt.valid && !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%s: Validate() = %v, want ErrInvalidData", tt.name, err)
+		}
+	}
+}
+
+func TestStoreRejectsInvalidCurve(t *testing.T) {
+	s := NewStore[any](nil, "players")
+	s.LevelCurve = func(uint32) uint64 { return 0 }
+
+	err := s.do("test", func(ctx context.Context) error {
+		t.Fatal("operation ran with an invalid curve")
+		return nil
+	})
+	if !errors.Is(err, ErrInvalidData) {
+		t.Errorf("do() = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestXPForLevel(t *testing.T) {
+	tests := []struct {
+		level uint32
+		want  uint64
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 200},
+		{3, 400},
+		{10, 1_800},
+		{50, 9_800},
+		{100, 19_800},
+		{math.MaxUint32, (math.MaxUint32 - 1) * 200},
+	}
+
+	for _, tt := range tests {
+		if got := DefaultLevelCurve(tt.level); got != tt.want {
+			t.Errorf("DefaultLevelCurve(%d) = %d, want %d", tt.level, got, tt.want)
+		}
+
+		if got := XPForLevel(tt.level); got != tt.want {
+			t.Errorf("XPForLevel(%d) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestProgressToLevel(t *testing.T) {
+	p := &PlayerState[any]{XP: 9_000, Level: 46}
+
+	tests := []struct {
+		target uint32
+		want   uint64
+	}{
+		{50, 800},
+		{46, 0},
+		{1, 0},
+	}
+
+	for _, tt := range tests {
+		if got := ProgressToLevel(p, tt.target); got != tt.want {
+			t.Errorf("ProgressToLevel(%d) = %d, want %d", tt.target, got, tt.want)
+		}
+	}
+
+	s := NewStore[any](nil, "players")
+	s.LevelCurve = func(level uint32) uint64 { return uint64(level-1) * 1_000 }
+	if got := s.XPForLevel(50); got != 49_000 {
+		t.Errorf("Store.XPForLevel(50) = %d, want 49000", got)
+	}
+
+	if got := s.ProgressToLevel(p, 50); got != 40_000 {
+		t.Errorf("Store.ProgressToLevel(50) = %d, want 40000", got)
+	}
+}
+
+func TestNextLevelXPMatchesSave(t *testing.T) {
+	custom := NewStore[any](nil, "players")
+	custom.LevelCurve = func(level uint32) uint64 {
+		n := uint64(level - 1)
+		return n * n * 100
+	}
+
+	stores := map[string]*Store[any]{
+		"default": NewStore[any](nil, "players"),
+		"custom":  custom,
+	}
+
+	for name, s := range stores {
+		m := NewMemoryStore(s)
+		p := NewPlayerState[any]("player", "phrase")
+		if _, err := m.Save(p, 0); err != nil {
+			t.Fatalf("%s: Save() = %v", name, err)
+		}
+
+		for level := uint32(1); level < 10; level++ {
+			next := s.NextLevelXP(p)
+
+			// One XP short of the threshold keeps the level
+			result, err := m.Save(p, next-p.XP-1)
+			if err != nil {
+				t.Fatalf("%s: Save() = %v", name, err)
+			}
+
+			if result.Level != level {
+				t.Fatalf("%s: level at %d xp = %d, want %d", name, result.XP, result.Level, level)
+			}
+
+			if into := s.XPIntoCurrentLevel(p); into != p.XP-s.XPForLevel(level) {
+				t.Errorf("%s: XPIntoCurrentLevel() = %d at %d xp, level %d", name, into, p.XP, level)
+			}
+
+			// Reaching it levels up with no XP into the new level
+			result, err = m.Save(p, 1)
+			if err != nil {
+				t.Fatalf("%s: Save() = %v", name, err)
+			}
+
+			if result.Level != level+1 || p.XP != next {
+				t.Fatalf("%s: Save() reached level %d at %d xp, want level %d at %d", name, result.Level, p.XP, level+1, next)
+			}
+
+			if into := s.XPIntoCurrentLevel(p); into != 0 {
+				t.Errorf("%s: XPIntoCurrentLevel() = %d at the level %d threshold, want 0", name, into, level+1)
+			}
+		}
+	}
+}
+
+func TestPlayerStateNextLevelXP(t *testing.T) {
+	p := &PlayerState[any]{XP: 450, Level: 3}
+
+	if got := p.NextLevelXP(); got != 600 {
+		t.Errorf("NextLevelXP() = %d, want 600", got)
+	}
+
+	if got := p.XPIntoCurrentLevel(); got != 50 {
+		t.Errorf("XPIntoCurrentLevel() = %d, want 50", got)
+	}
+
+	// A starting level above the player's XP has no progress into it
+	p = &PlayerState[any]{XP: 100, Level: 5}
+	if got := p.XPIntoCurrentLevel(); got != 0 {
+		t.Errorf("XPIntoCurrentLevel() = %d, want 0", got)
+	}
+}
@@ -0,0 +1,457 @@
+package ghostplay
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// PlayerStore is the core player API, implemented by Store and by
+// MemoryStore, so game logic can depend on it and be unit tested against a
+// MemoryStore without a database.
+type PlayerStore[T any] interface {
+	InitPlayer(id uuid.UUID, username, phrase string) error
+	PlayerExists(id uuid.UUID) (bool, error)
+	GetUserStateByID(id uuid.UUID) (*PlayerState[T], error)
+	GetUserStateByPhrase(phrase string) (*PlayerState[T], error)
+	Save(p *PlayerState[T], xpIncrease uint64) (SaveResult, error)
+	GetFlags(id uuid.UUID) (map[string]bool, error)
+	SetFlagDirect(id uuid.UUID, key string, value bool) error
+	GetLeaderboard(limit int) ([]Leader, error)
+	SoftDeletePlayer(id uuid.UUID) error
+}
+
+var (
+	_ PlayerStore[any] = (*Store[any])(nil)
+	_ PlayerStore[any] = (*MemoryStore[any])(nil)
+)
+
+// MemoryStore is an in-memory PlayerStore for tests. It applies the
+// configuration of the Store it was created from, so XP accumulation,
+// level-ups, flag checks, validation, and errors such as ErrPlayerNotFound
+// match a real Store's; the Store's connection, table, columns, and Season
+// are ignored. Extra data is kept encoded with the Store's Codec, so players
+// read back never share maps or slices with the ones saved.
+// A MemoryStore is safe for concurrent use.
+type MemoryStore[T any] struct {
+	config *Store[T]
+
+	mu      sync.Mutex
+	players map[uuid.UUID]*memoryPlayer[T]
+}
+
+// memoryPlayer is a stored player; state.ExtraData is unused in favor of
+// the encoded extraData.
+type memoryPlayer[T any] struct {
+	state     PlayerState[T]
+	extraData []byte
+}
+
+// NewMemoryStore returns an empty MemoryStore that behaves like config, or
+// like a Store with the default configuration when config is nil.
+// Configuration changes to config after the call apply to the MemoryStore too.
+func NewMemoryStore[T any](config *Store[T]) *MemoryStore[T] {
+	if config == nil {
+		config = NewStore[T](nil, "")
+	}
+	return &MemoryStore[T]{
+		config:  config,
+		players: make(map[uuid.UUID]*memoryPlayer[T]),
+	}
+}
+
+// live reports whether a stored player is visible to the getters.
+func (m *MemoryStore[T]) live(p *memoryPlayer[T]) bool {
+	return p.state.DeletedAt == nil || m.config.IncludeDeleted
+}
+
+// load returns a copy of a stored player as the getters return it.
+func (m *MemoryStore[T]) load(p *memoryPlayer[T]) (*PlayerState[T], error) {
+	state := p.state.Clone()
+	if state.Flags == nil {
+		state.Flags = make(map[string]bool)
+	}
+
+	if err := m.config.decodeJSONColumns(state, nil, p.extraData); err != nil {
+		return nil, err
+	}
+	return state, nil
+}
+
+// byPhrase returns the stored player holding phrase, live or not.
+func (m *MemoryStore[T]) byPhrase(phrase string) *memoryPlayer[T] {
+	for _, p := range m.players {
+		if p.state.Phrase == phrase {
+			return p
+		}
+	}
+	return nil
+}
+
+// InitPlayer creates a new player with the configured starting level, XP,
+// and extra data.
+// ErrPlayerExists is returned if the id or phrase is already registered.
+func (m *MemoryStore[T]) InitPlayer(id uuid.UUID, username, phrase string) error {
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if username == "" || phrase == "" {
+		return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := m.config.checkLengths(username, phrase); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := PlayerState[T]{
+		ID:       id,
+		UserName: username,
+		Phrase:   phrase,
+		Level:    m.config.StartingLevel,
+		XP:       m.config.StartingXP,
+	}
+	return m.create(&p, nil)
+}
+
+// create stores a new player. When extraData is nil, the configured default
+// extra data is stored. The caller must hold m.mu.
+func (m *MemoryStore[T]) create(p *PlayerState[T], extraData []byte) error {
+	phrase, err := m.config.storedPhrase(p.Phrase)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := m.players[p.ID]; ok {
+		return fmt.Errorf("%w: id %s", ErrPlayerExists, p.ID)
+	}
+
+	if m.byPhrase(phrase) != nil {
+		return fmt.Errorf("%w: %w: %q", ErrPlayerExists, ErrPhraseTaken, p.Phrase)
+	}
+
+	if extraData == nil {
+		extraData, err = m.config.defaultExtraData()
+		if err != nil {
+			return err
+		}
+	}
+
+	stored := p.Clone()
+	var zero T
+	stored.ExtraData = zero
+	stored.Phrase = phrase
+	if stored.Flags == nil {
+		stored.Flags = make(map[string]bool)
+	}
+	if stored.LastUpdated.IsZero() {
+		stored.LastUpdated = m.config.now()
+	}
+
+	m.players[p.ID] = &memoryPlayer[T]{state: *stored, extraData: extraData}
+	return nil
+}
+
+// PlayerExists reports whether a player with the given UUID is stored,
+// including soft-deleted players.
+func (m *MemoryStore[T]) PlayerExists(id uuid.UUID) (bool, error) {
+	if id == uuid.Nil {
+		return false, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.players[id]
+	return ok, nil
+}
+
+// GetUserStateByID returns a copy of the player with the given UUID.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (m *MemoryStore[T]) GetUserStateByID(id uuid.UUID) (*PlayerState[T], error) {
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok || !m.live(p) {
+		return nil, ErrPlayerNotFound
+	}
+	return m.load(p)
+}
+
+// GetUserStateByPhrase returns a copy of the player with the given phrase.
+// ErrPlayerNotFound is returned if no player has the phrase.
+func (m *MemoryStore[T]) GetUserStateByPhrase(phrase string) (*PlayerState[T], error) {
+	if phrase == "" {
+		return nil, fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	phrase, err := m.config.storedPhrase(phrase)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.byPhrase(phrase)
+	if p == nil || !m.live(p) {
+		return nil, ErrPlayerNotFound
+	}
+	return m.load(p)
+}
+
+// Save awards xpIncrease XP to the player and stores their flags and extra
+// data, creating the player if they don't exist, as Store.Save does.
+func (m *MemoryStore[T]) Save(p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	s := m.config
+	if p == nil {
+		return SaveResult{}, fmt.Errorf("%w: player cannot be nil", ErrInvalidData)
+	}
+
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+
+	if p.UserName == "" || p.Phrase == "" {
+		return SaveResult{}, fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths(p.UserName, p.Phrase); err != nil {
+		return SaveResult{}, err
+	}
+
+	if err := s.checkFlags(p.Flags); err != nil {
+		return SaveResult{}, err
+	}
+
+	if s.Validate != nil {
+		if err := s.Validate(p.ExtraData); err != nil {
+			return SaveResult{}, fmt.Errorf("%w: extra data: %w", ErrInvalidData, err)
+		}
+	}
+
+	result, err := m.save(p, xpIncrease)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	s.afterSave(p.ID, result)
+	return result, nil
+}
+
+// save performs Save once its input has been validated.
+func (m *MemoryStore[T]) save(p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	s := m.config
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var player *PlayerState[T]
+	stored, ok := m.players[p.ID]
+	if ok && m.live(stored) {
+		player = &stored.state
+	}
+
+	result, err := s.nextState(player, p, xpIncrease)
+	if err != nil {
+		return SaveResult{}, err
+	}
+	now := s.now()
+
+	if result.Created {
+		if p.Flags == nil {
+			p.Flags = make(map[string]bool)
+		}
+
+		if s.DefaultExtraData != nil && reflect.ValueOf(&p.ExtraData).Elem().IsZero() {
+			p.ExtraData = s.DefaultExtraData()
+		}
+
+		extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+		if err != nil {
+			return SaveResult{}, err
+		}
+
+		p.Level = result.Level
+		p.XP = result.XP
+		p.LastUpdated = now
+
+		if err := m.create(p, extraData); err != nil {
+			return SaveResult{}, fmt.Errorf("failed to initialize player: %w", err)
+		}
+		return result, nil
+	}
+
+	if s.MinAwardInterval > 0 && xpIncrease > 0 && stored.state.LastUpdated.After(now.Add(-s.MinAwardInterval)) {
+		return SaveResult{}, ErrAwardTooSoon
+	}
+
+	extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	if _, err := marshalFlags(s.codec(), p.Flags); err != nil {
+		return SaveResult{}, err
+	}
+
+	p.XP = result.XP
+	p.Level = result.Level
+	p.LastUpdated = now
+
+	// Like the UPDATE, Save leaves the stored username and phrase alone
+	stored.state.XP = p.XP
+	stored.state.Level = p.Level
+	stored.state.LastUpdated = now
+	stored.state.UpdatedBy = p.UpdatedBy
+	stored.state.Flags = p.Clone().Flags
+	stored.extraData = extraData
+	return result, nil
+}
+
+// GetFlags returns a copy of the player's flags, empty if none are set.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (m *MemoryStore[T]) GetFlags(id uuid.UUID) (map[string]bool, error) {
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p, ok := m.players[id]
+	if !ok || !m.live(p) {
+		return nil, ErrPlayerNotFound
+	}
+
+	flags := make(map[string]bool, len(p.state.Flags))
+	for key, value := range p.state.Flags {
+		flags[key] = value
+	}
+	return flags, nil
+}
+
+// SetFlagDirect sets a single flag on a player without touching anything
+// else. ErrPlayerNotFound is returned if no player has the given UUID.
+func (m *MemoryStore[T]) SetFlagDirect(id uuid.UUID, key string, value bool) error {
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if key == "" {
+		return fmt.Errorf("%w: flag key cannot be empty", ErrInvalidData)
+	}
+
+	if err := m.config.checkFlagKey(key); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	p, ok := m.players[id]
+	if !ok || !m.live(p) {
+		m.mu.Unlock()
+		return ErrPlayerNotFound
+	}
+
+	if p.state.Flags == nil {
+		p.state.Flags = make(map[string]bool)
+	}
+	p.state.Flags[key] = value
+	m.mu.Unlock()
+
+	m.config.afterSave(id, SaveResult{})
+	return nil
+}
+
+// GetLeaderboard returns the top players by XP, ordering players with equal
+// XP by the Store's Tiebreaker.
+func (m *MemoryStore[T]) GetLeaderboard(limit int) ([]Leader, error) {
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	compare, err := m.tiebreak()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	var players []*PlayerState[T]
+	for _, p := range m.players {
+		if m.live(p) {
+			players = append(players, &p.state)
+		}
+	}
+
+	slices.SortFunc(players, func(a, b *PlayerState[T]) int {
+		if c := cmp.Compare(b.XP, a.XP); c != 0 {
+			return c
+		}
+		return compare(a, b)
+	})
+
+	var leaders []Leader
+	for _, p := range players[:min(limit, len(players))] {
+		leaders = append(leaders, Leader{UserName: p.UserName, Level: p.Level, XP: p.XP})
+	}
+	m.mu.Unlock()
+
+	return leaders, nil
+}
+
+// tiebreak returns the comparison matching the Store's Tiebreaker.
+func (m *MemoryStore[T]) tiebreak() (func(a, b *PlayerState[T]) int, error) {
+	byID := func(a, b *PlayerState[T]) int {
+		return slices.Compare(a.ID[:], b.ID[:])
+	}
+
+	switch m.config.Tiebreaker {
+	case TiebreakLevelThenOldest:
+		return func(a, b *PlayerState[T]) int {
+			if c := cmp.Compare(b.Level, a.Level); c != 0 {
+				return c
+			}
+			if c := a.LastUpdated.Compare(b.LastUpdated); c != 0 {
+				return c
+			}
+			return byID(a, b)
+		}, nil
+	case TiebreakID:
+		return byID, nil
+	}
+	return nil, fmt.Errorf("%w: unknown leaderboard tiebreaker %d", ErrInvalidData, m.config.Tiebreaker)
+}
+
+// SoftDeletePlayer marks a player as deleted, hiding them from the getters
+// and leaderboard. ErrPlayerNotFound is returned if no live player has the
+// given UUID.
+func (m *MemoryStore[T]) SoftDeletePlayer(id uuid.UUID) error {
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	m.mu.Lock()
+	p, ok := m.players[id]
+	if !ok || p.state.DeletedAt != nil {
+		m.mu.Unlock()
+		return ErrPlayerNotFound
+	}
+
+	now := m.config.now()
+	p.state.DeletedAt = &now
+	m.mu.Unlock()
+
+	m.config.afterSave(id, SaveResult{})
+	return nil
+}
@@ -0,0 +1,26 @@
+package ghostplay
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMemoryStoreInitPlayerConflicts(t *testing.T) {
+	m := NewMemoryStore[any](nil)
+	id := uuid.New()
+	if err := m.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	err := m.InitPlayer(id, "other", "other phrase")
+	if !errors.Is(err, ErrPlayerExists) || errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with a duplicate id = %v, want ErrPlayerExists only", err)
+	}
+
+	err = m.InitPlayer(uuid.New(), "other", "phrase")
+	if !errors.Is(err, ErrPlayerExists) || !errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with a duplicate phrase = %v, want ErrPlayerExists and ErrPhraseTaken", err)
+	}
+}
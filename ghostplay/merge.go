@@ -0,0 +1,159 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// MergePlayers folds the player remove into the player keep and deletes
+// remove, e.g., when a user accidentally created two accounts.
+func MergePlayers[T any](db *sql.DB, dbTableName string, keep, remove uuid.UUID, mergeExtra func(keep, remove T) T) error {
+	return NewStore[T](db, dbTableName).MergePlayers(keep, remove, mergeExtra)
+}
+
+// MergePlayers folds the player remove into the player keep and permanently
+// deletes remove, all in one transaction with both rows locked.
+//
+// The kept player ends up with the sum of both players' XP, the higher of
+// their levels, and every flag either player had set to true. Their extra
+// data is mergeExtra(keep, remove), or left as is when mergeExtra is nil.
+// When AutoLevel is enabled the level is then raised to match the combined
+// XP. Phrases added to remove move to keep, and the XP gained is recorded in
+// the audit table. The kept player's UpdatedBy is cleared.
+// ErrPlayerNotFound is returned if either player is missing.
+func (s *Store[T]) MergePlayers(keep, remove uuid.UUID, mergeExtra func(keep, remove T) T) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if keep == uuid.Nil || remove == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if keep == remove {
+		return fmt.Errorf("%w: cannot merge a player into itself", ErrInvalidData)
+	}
+
+	var phrases string
+	if s.PhraseTable != "" {
+		var err error
+		if phrases, err = s.phraseTable(); err != nil {
+			return err
+		}
+	}
+
+	var merged SaveResult
+	err := s.do("merge_players", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Lock in id order so opposing merges can't deadlock
+		query := s.expand(`
+			SELECT {player_columns}
+			FROM {table}
+			WHERE {id} = ANY($1::uuid[]) AND {live}
+			ORDER BY {id}
+			FOR UPDATE`)
+
+		players, err := s.queryPlayers(ctx, tx, query, s.seasonArgs(uuidArray([]uuid.UUID{keep, remove}))...)
+		if err != nil {
+			return err
+		}
+
+		var kept, removed *PlayerState[T]
+		for _, p := range players {
+			if p.ID == keep {
+				kept = p
+			} else {
+				removed = p
+			}
+		}
+
+		if kept == nil {
+			return fmt.Errorf("%w: %s", ErrPlayerNotFound, keep)
+		}
+
+		if removed == nil {
+			return fmt.Errorf("%w: %s", ErrPlayerNotFound, remove)
+		}
+
+		merged = SaveResult{PreviousXP: kept.XP, PreviousLevel: kept.Level}
+		kept.XP += removed.XP
+		kept.Level = max(kept.Level, removed.Level)
+		if s.AutoLevel {
+			kept.Level = max(kept.Level, s.curve().levelForXP(kept.XP))
+		}
+
+		for key, value := range removed.Flags {
+			kept.Flags[key] = kept.Flags[key] || value
+		}
+
+		if mergeExtra != nil {
+			kept.ExtraData = mergeExtra(kept.ExtraData, removed.ExtraData)
+		}
+
+		extraData, err := marshalExtraData(s.codec(), kept.ExtraData)
+		if err != nil {
+			return err
+		}
+
+		flags, err := marshalFlags(s.codec(), kept.Flags)
+		if err != nil {
+			return err
+		}
+
+		// Free the removed player's phrase before anything else can claim it
+		if _, err := tx.ExecContext(ctx, s.expand(`DELETE FROM {table} WHERE {id} = $1 AND {season}`), s.seasonArgs(remove)...); err != nil {
+			return fmt.Errorf("failed to delete merged player: %w", err)
+		}
+
+		if phrases != "" {
+			move := `UPDATE ` + phrases + ` SET player_id = $1 WHERE player_id = $2`
+			if _, err := tx.ExecContext(ctx, move, keep, remove); err != nil {
+				return fmt.Errorf("failed to move merged phrases: %w", err)
+			}
+		}
+
+		update := s.expand(`
+			UPDATE {table}
+			SET {level} = $1,
+				{xp} = $2,
+				{extra_data} = $3,
+				{flags} = $4,
+				{last_updated} = $5,
+				{updated_by} = NULL
+			WHERE {id} = $6 AND {season}`)
+
+		err = s.execWithAudit(ctx, tx, keep, int64(removed.XP), kept.XP, kept.Level, nil, update, s.seasonArgs(
+			kept.Level,
+			kept.XP,
+			extraData,
+			flags,
+			s.now(),
+			keep,
+		)...)
+		if err != nil {
+			return fmt.Errorf("failed to update merged player: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit merge: %w", err)
+		}
+
+		merged.XP, merged.Level = kept.XP, kept.Level
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(keep, merged)
+	s.afterSave(remove, SaveResult{})
+	return nil
+}
@@ -0,0 +1,145 @@
+package ghostplay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// PlayerChange is the JSON payload sent on the Store's NotifyChannel when a
+// player's XP or level is written.
+type PlayerChange struct {
+	ID    uuid.UUID `json:"id"`
+	XP    uint64    `json:"xp"`
+	Level uint32    `json:"level"`
+}
+
+// notifyChange sends change on the Store's NotifyChannel when one is set.
+// Postgres delivers the notification only once the surrounding transaction
+// commits, so callers run it in the transaction that made the change.
+func (s *Store[T]) notifyChange(ctx context.Context, q Querier, change PlayerChange) error {
+	if s.NotifyChannel == "" {
+		return nil
+	}
+
+	payload, err := json.Marshal(change)
+	if err != nil {
+		return fmt.Errorf("failed to marshal player change: %w", err)
+	}
+
+	if _, err := q.ExecContext(ctx, `SELECT pg_notify($1, $2)`, s.NotifyChannel, string(payload)); err != nil {
+		return fmt.Errorf("failed to notify player change: %w", err)
+	}
+	return nil
+}
+
+// listenPingInterval is how long Listen waits without a notification before
+// pinging the server, so a dead connection is noticed and reopened.
+const listenPingInterval = 90 * time.Second
+
+// Listen decodes player change notifications sent on channel, typically the
+// Store's NotifyChannel, until ctx is done. listener holds the LISTEN
+// connection, since database/sql has no notification API; Listen starts
+// listening on channel and stops again when it returns, but doesn't close
+// listener:
+//
+//	listener := pq.NewListener(dsn, time.Second, time.Minute, nil)
+//	defer listener.Close()
+//	changes, errs := ghostplay.Listen(ctx, listener, "player_changes")
+//
+// listener reconnects on its own after the connection drops; notifications
+// sent while it was down are lost. Payloads that don't decode as a
+// PlayerChange are skipped. When listening stops, the error that stopped it
+// is sent on the error channel and both channels are closed.
+func Listen(ctx context.Context, listener *pq.Listener, channel string) (<-chan PlayerChange, <-chan error) {
+	changes := make(chan PlayerChange)
+	errs := make(chan error, 1)
+
+	if listener == nil {
+		errs <- fmt.Errorf("%w: nil listener", ErrDatabaseConnection)
+		close(changes)
+		close(errs)
+		return changes, errs
+	}
+
+	if channel == "" {
+		errs <- fmt.Errorf("%w: notification channel cannot be empty", ErrInvalidData)
+		close(changes)
+		close(errs)
+		return changes, errs
+	}
+
+	go func() {
+		defer close(changes)
+		defer close(errs)
+
+		// listener.Listen blocks until listener is connected
+		listening := make(chan error, 1)
+		go func() {
+			listening <- listener.Listen(channel)
+		}()
+
+		select {
+		case err := <-listening:
+			if err != nil {
+				errs <- fmt.Errorf("failed to listen on %q: %w", channel, err)
+				return
+			}
+		case <-ctx.Done():
+			errs <- ctx.Err()
+			go func() {
+				if <-listening == nil {
+					listener.Unlisten(channel)
+				}
+			}()
+			return
+		}
+		defer listener.Unlisten(channel)
+
+		ping := time.NewTimer(listenPingInterval)
+		defer ping.Stop()
+
+		for {
+			var n *pq.Notification
+			select {
+			case n = <-listener.NotificationChannel():
+			case <-ping.C:
+				// A failed ping makes listener reconnect
+				go listener.Ping()
+				ping.Reset(listenPingInterval)
+				continue
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+
+			if !ping.Stop() {
+				<-ping.C
+			}
+			ping.Reset(listenPingInterval)
+
+			// A nil notification signals a reconnect
+			if n == nil || n.Channel != channel {
+				continue
+			}
+
+			var change PlayerChange
+			if err := json.Unmarshal([]byte(n.Extra), &change); err != nil {
+				continue
+			}
+
+			select {
+			case changes <- change:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return changes, errs
+}
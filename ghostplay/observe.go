@@ -0,0 +1,15 @@
+package ghostplay
+
+import "time"
+
+// QueryObserver receives the outcome of every database operation a Store
+// performs, e.g., to record latency and error metrics.
+//
+// op names the operation, such as "save", "get_by_id", or "leaderboard".
+// dur covers the whole operation including any retries, and err is the error
+// it finished with, before the Store maps it for the caller; a lookup that
+// finds nothing reports sql.ErrNoRows. ObserveQuery is called synchronously,
+// so it should return quickly, and must be safe for concurrent use.
+type QueryObserver interface {
+	ObserveQuery(op string, dur time.Duration, err error)
+}
@@ -0,0 +1,24 @@
+package ghostplay
+
+import "errors"
+
+// SQLSTATE codes the package reacts to.
+const (
+	sqlStateUniqueViolation = "23505"
+	sqlStateUndefinedTable  = "42P01"
+
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+// sqlStateError is implemented by the errors of common Postgres drivers
+// (lib/pq and pgx) and exposes the SQLSTATE code of a failed statement.
+type sqlStateError interface {
+	SQLState() string
+}
+
+// hasSQLState reports whether err was caused by a Postgres error with the given code.
+func hasSQLState(err error, code string) bool {
+	var pgErr sqlStateError
+	return errors.As(err, &pgErr) && pgErr.SQLState() == code
+}
@@ -0,0 +1,55 @@
+package ghostplay
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PhraseHasher transforms a player's phrase before it is stored or looked up,
+// so the phrase column doesn't hold login secrets in plaintext.
+//
+// Lookups hash the given phrase and compare it for equality, so a hasher must
+// be deterministic: the same phrase always yields the same hash. Salted,
+// adaptive hashes such as bcrypt can't be searched this way; using one would
+// require a separate deterministic lookup column alongside the bcrypt hash.
+type PhraseHasher interface {
+	HashPhrase(phrase string) (string, error)
+}
+
+// SHA256PhraseHasher stores phrases as hex-encoded SHA-256 digests.
+// Pepper, when set, is a server-side secret mixed into every digest to make
+// precomputed dictionary attacks against a leaked table harder.
+type SHA256PhraseHasher struct {
+	Pepper string
+}
+
+// HashPhrase returns the hex-encoded SHA-256 digest of the peppered phrase.
+func (h SHA256PhraseHasher) HashPhrase(phrase string) (string, error) {
+	sum := sha256.Sum256([]byte(h.Pepper + phrase))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizePhrase applies the Store's case folding to phrase.
+func (s *Store[T]) normalizePhrase(phrase string) string {
+	if s.CaseInsensitivePhrases {
+		return strings.ToLower(phrase)
+	}
+	return phrase
+}
+
+// storedPhrase returns phrase as it is written to and searched for in the
+// phrase column.
+func (s *Store[T]) storedPhrase(phrase string) (string, error) {
+	phrase = s.normalizePhrase(phrase)
+	if s.PhraseHasher == nil {
+		return phrase, nil
+	}
+
+	hashed, err := s.PhraseHasher.HashPhrase(phrase)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash phrase: %w", err)
+	}
+	return hashed, nil
+}
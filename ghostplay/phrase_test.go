@@ -0,0 +1,66 @@
+package ghostplay
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCaseInsensitivePhrases(t *testing.T) {
+	s := NewStore[any](nil, "players")
+	s.CaseInsensitivePhrases = true
+	m := NewMemoryStore(s)
+
+	id := uuid.New()
+	if err := m.InitPlayer(id, "player", "DragonFly"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	for _, phrase := range []string{"DragonFly", "dragonfly", "DRAGONFLY", "dRaGoNfLy"} {
+		p, err := m.GetUserStateByPhrase(phrase)
+		if err != nil {
+			t.Errorf("GetUserStateByPhrase(%q) = %v", phrase, err)
+			continue
+		}
+
+		if p.ID != id || p.Phrase != "dragonfly" {
+			t.Errorf("GetUserStateByPhrase(%q) = id %s, phrase %q; want %s, \"dragonfly\"", phrase, p.ID, p.Phrase, id)
+		}
+	}
+
+	if err := m.InitPlayer(uuid.New(), "other", "DRAGONFLY"); !errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with the phrase in other case = %v, want ErrPhraseTaken", err)
+	}
+}
+
+func TestCaseSensitivePhrases(t *testing.T) {
+	m := NewMemoryStore[any](nil)
+	if err := m.InitPlayer(uuid.New(), "player", "DragonFly"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	if _, err := m.GetUserStateByPhrase("dragonfly"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Errorf("GetUserStateByPhrase() = %v, want ErrPlayerNotFound", err)
+	}
+}
+
+func TestCaseInsensitivePhraseQuery(t *testing.T) {
+	var searched any
+	db := fakeDB(t, func(_ string, args []driver.NamedValue) ([][]driver.Value, error) {
+		searched = args[0].Value
+		return nil, nil
+	})
+
+	s := NewStore[any](db, "players")
+	s.CaseInsensitivePhrases = true
+
+	if _, err := s.GetUserStateByPhrase("DragonFly"); !errors.Is(err, ErrPlayerNotFound) {
+		t.Fatalf("GetUserStateByPhrase() = %v, want ErrPlayerNotFound", err)
+	}
+
+	if searched != "dragonfly" {
+		t.Errorf("searched for %v, want dragonfly", searched)
+	}
+}
@@ -0,0 +1,385 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// InitPhraseTable creates the table of additional player phrases if it
+// doesn't exist. Set the Store's PhraseTable to the same name to use it.
+func InitPhraseTable(db *sql.DB, phraseTableName string) error {
+	if db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	table, err := quoteTableName(phraseTableName)
+	if err != nil {
+		return err
+	}
+
+	index, err := indexName(phraseTableName, "player_idx")
+	if err != nil {
+		return err
+	}
+
+	query := fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			phrase VARCHAR(255) PRIMARY KEY,
+			player_id UUID NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		)
+	`, table)
+
+	_, err = db.Exec(query)
+	if err != nil {
+		return fmt.Errorf("failed to create phrase table: %w", err)
+	}
+
+	indexQuery := fmt.Sprintf(`
+		CREATE INDEX IF NOT EXISTS %s
+		ON %s (player_id)
+	`, index, table)
+
+	_, err = db.Exec(indexQuery)
+	if err != nil {
+		return fmt.Errorf("failed to create phrase table index: %w", err)
+	}
+	return nil
+}
+
+// phraseTable returns the Store's PhraseTable quoted for use in SQL.
+func (s *Store[T]) phraseTable() (string, error) {
+	if s.PhraseTable == "" {
+		return "", fmt.Errorf("%w: phrase table is not configured", ErrInvalidData)
+	}
+	return quoteTableName(s.PhraseTable)
+}
+
+// phraseMatch returns a predicate matching the player who holds the phrase
+// bound to $1, in either the player table or the Store's PhraseTable.
+func (s *Store[T]) phraseMatch() (string, error) {
+	if s.PhraseTable == "" {
+		return "{phrase} = $1", nil
+	}
+
+	table, err := s.phraseTable()
+	if err != nil {
+		return "", err
+	}
+	return "({phrase} = $1 OR {id} IN (SELECT player_id FROM " + table + " WHERE phrase = $1))", nil
+}
+
+// AddPhrase gives a player an additional phrase they can be looked up by.
+func AddPhrase(db *sql.DB, dbTableName, phraseTableName string, id uuid.UUID, phrase string) error {
+	s := NewStore[any](db, dbTableName)
+	s.PhraseTable = phraseTableName
+	return s.AddPhrase(id, phrase)
+}
+
+// AddPhrase gives a player an additional phrase, stored in the Store's
+// PhraseTable, that GetUserStateByPhrase finds them by. The player's own
+// phrase is unchanged.
+//
+// ErrPhraseTaken is returned if any player already holds the phrase, and
+// ErrPlayerNotFound if no player has the given UUID. A player created with
+// the same phrase at the same moment can slip past the check, since the two
+// tables' uniqueness is enforced separately.
+func (s *Store[T]) AddPhrase(id uuid.UUID, phrase string) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if phrase == "" {
+		return fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths("", phrase); err != nil {
+		return err
+	}
+
+	table, err := s.phraseTable()
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.storedPhrase(phrase)
+	if err != nil {
+		return err
+	}
+
+	// Insert only for a live player whose phrase doesn't collide
+	query := s.expand(`
+		INSERT INTO ` + table + ` (phrase, player_id)
+		SELECT $2::text, $1::uuid
+		WHERE EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {live})
+			AND NOT EXISTS(SELECT 1 FROM {table} WHERE {phrase} = $2)
+		`)
+
+	exists := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {live})
+		`)
+
+	err = s.do("add_phrase", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id, stored)...)
+		if hasSQLState(err, sqlStateUniqueViolation) {
+			return fmt.Errorf("%w: %q", ErrPhraseTaken, phrase)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to add phrase: %w", err)
+		}
+
+		if requireRowAffected(result) == nil {
+			return nil
+		}
+
+		// Nothing was inserted; tell a missing player apart from a taken phrase
+		var found bool
+		if err := s.db.QueryRowContext(ctx, exists, s.seasonArgs(id)...).Scan(&found); err != nil {
+			return fmt.Errorf("failed to check player existence: %w", err)
+		}
+
+		if !found {
+			return ErrPlayerNotFound
+		}
+		return fmt.Errorf("%w: %q", ErrPhraseTaken, phrase)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// RemovePhrase takes away a phrase added to a player with AddPhrase.
+func RemovePhrase(db *sql.DB, dbTableName, phraseTableName string, id uuid.UUID, phrase string) error {
+	s := NewStore[any](db, dbTableName)
+	s.PhraseTable = phraseTableName
+	return s.RemovePhrase(id, phrase)
+}
+
+// RemovePhrase takes away a phrase added to a player with AddPhrase. The
+// player's own phrase can't be removed this way. ErrPlayerNotFound is
+// returned if the player doesn't hold the phrase in the Store's PhraseTable.
+func (s *Store[T]) RemovePhrase(id uuid.UUID, phrase string) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if phrase == "" {
+		return fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	table, err := s.phraseTable()
+	if err != nil {
+		return err
+	}
+
+	stored, err := s.storedPhrase(phrase)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		DELETE FROM ` + table + `
+		WHERE player_id = $1 AND phrase = $2
+		`
+
+	err = s.do("remove_phrase", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, id, stored)
+		if err != nil {
+			return fmt.Errorf("failed to remove phrase: %w", err)
+		}
+
+		if err := requireRowAffected(result); err != nil {
+			return fmt.Errorf("%w: phrase %q is not held by player %s", err, phrase, id)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// releasedPhrasePrefix starts the placeholder phrases ReleasePhrase stores.
+// Each placeholder ends in a random UUID, so no caller can guess one, and a
+// PhraseHasher's output never matches one.
+const releasedPhrasePrefix = "released-"
+
+// ReleasePhrase detaches a player's phrase so another player can claim it.
+func ReleasePhrase(db *sql.DB, dbTableName string, id uuid.UUID) error {
+	return NewStore[any](db, dbTableName).ReleasePhrase(id)
+}
+
+// ClaimPhrase assigns a phrase to a player, releasing it from any player
+// that holds it, for account recovery.
+func ClaimPhrase(db *sql.DB, dbTableName string, id uuid.UUID, phrase string) error {
+	return NewStore[any](db, dbTableName).ClaimPhrase(id, phrase)
+}
+
+// ReleasePhrase detaches a player's phrase, e.g., once support has confirmed
+// it belongs to someone else. Since the phrase column can't be empty, it is
+// replaced with a generated placeholder that no lookup matches, so the player
+// can no longer be found by phrase until given a new one with ClaimPhrase or
+// Save. Phrases added with AddPhrase are kept. Soft-deleted players can be
+// released too. ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) ReleasePhrase(id uuid.UUID) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {phrase} = $2
+		WHERE {id} = $1 AND {season}
+		`)
+
+	err := s.do("release_phrase", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(id, releasedPhrasePrefix+uuid.NewString())...)
+		if err != nil {
+			return fmt.Errorf("failed to release phrase: %w", err)
+		}
+
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	return nil
+}
+
+// ClaimPhrase assigns phrase to the player with the given UUID in one
+// transaction. A player already holding it, soft-deleted or not, has it
+// released as with ReleasePhrase, and it is removed from the Store's
+// PhraseTable when one is configured. The claiming player's previous phrase
+// is freed. ErrPlayerNotFound is returned if no live player has the given
+// UUID, and ErrPhraseTaken if another player took the phrase concurrently.
+func (s *Store[T]) ClaimPhrase(id uuid.UUID, phrase string) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if phrase == "" {
+		return fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths("", phrase); err != nil {
+		return err
+	}
+
+	stored, err := s.storedPhrase(phrase)
+	if err != nil {
+		return err
+	}
+
+	var removeAdded string
+	if s.PhraseTable != "" {
+		table, err := s.phraseTable()
+		if err != nil {
+			return err
+		}
+		removeAdded = `DELETE FROM ` + table + ` WHERE phrase = $1 RETURNING player_id`
+	}
+
+	lock := s.expand(`
+		SELECT {id}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		FOR UPDATE
+		`)
+
+	release := s.expand(`
+		UPDATE {table}
+		SET {phrase} = $3
+		WHERE {phrase} = $1 AND {id} <> $2 AND {season}
+		RETURNING {id}
+		`)
+
+	assign := s.expand(`
+		UPDATE {table}
+		SET {phrase} = $1
+		WHERE {id} = $2 AND {season}
+		`)
+
+	// Players losing the phrase are collected for AfterSave
+	var released []uuid.UUID
+	err = s.do("claim_phrase", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		var locked uuid.UUID
+		err = tx.QueryRowContext(ctx, lock, s.seasonArgs(id)...).Scan(&locked)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrPlayerNotFound
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to lock player: %w", err)
+		}
+
+		released, err = queryIDs(ctx, tx, release, s.seasonArgs(stored, id, releasedPhrasePrefix+uuid.NewString())...)
+		if err != nil {
+			return fmt.Errorf("failed to release phrase: %w", err)
+		}
+
+		if removeAdded != "" {
+			holders, err := queryIDs(ctx, tx, removeAdded, stored)
+			if err != nil {
+				return fmt.Errorf("failed to remove added phrase: %w", err)
+			}
+			released = append(released, holders...)
+		}
+
+		_, err = tx.ExecContext(ctx, assign, s.seasonArgs(stored, id)...)
+		if hasSQLState(err, sqlStateUniqueViolation) {
+			return fmt.Errorf("%w: %q", ErrPhraseTaken, phrase)
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to claim phrase: %w", err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit phrase claim: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(id, SaveResult{})
+	for _, holder := range released {
+		s.afterSave(holder, SaveResult{})
+	}
+	return nil
+}
@@ -0,0 +1,166 @@
+package ghostplay
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// testDSNEnv names the environment variable holding the connection string of
+// a Postgres database the tests may create tables in. Tests needing a real
+// database are skipped when it is unset.
+const testDSNEnv = "GHOSTPLAY_TEST_DSN"
+
+// testDB returns a connection to the test database, skipping t if there is
+// none.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDSNEnv)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("failed to open test database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := db.Ping(); err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	return db
+}
+
+// testStore returns a Store for a new player state table in the test
+// database, dropped when t ends, skipping t if there is no test database.
+func testStore[T any](t *testing.T) *Store[T] {
+	t.Helper()
+
+	db := testDB(t)
+	table := "ghostplay_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	s := NewStore[T](db, table)
+	if err := s.InitPlayerStateTable(); err != nil {
+		t.Fatalf("InitPlayerStateTable() = %v", err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DROP TABLE IF EXISTS ` + table); err != nil {
+			t.Errorf("failed to drop %s: %v", table, err)
+		}
+	})
+	return s
+}
+
+func TestCustomSchema(t *testing.T) {
+	db := testDB(t)
+	schema := "ghostplay_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+
+	if _, err := db.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatalf("failed to create schema %s: %v", schema, err)
+	}
+
+	t.Cleanup(func() {
+		if _, err := db.Exec(`DROP SCHEMA IF EXISTS ` + schema + ` CASCADE`); err != nil {
+			t.Errorf("failed to drop %s: %v", schema, err)
+		}
+	})
+
+	s := NewStore[any](db, schema+".player_state")
+	if err := s.InitPlayerStateTable(); err != nil {
+		t.Fatalf("InitPlayerStateTable() = %v", err)
+	}
+
+	if err := s.VerifySchema(); err != nil {
+		t.Errorf("VerifySchema() = %v", err)
+	}
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	p, err := s.GetUserStateByID(id)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	p.Flags["premium"] = true
+	if _, err := s.Save(p, 250); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	p, err = s.GetUserStateByPhrase("phrase")
+	if err != nil {
+		t.Fatalf("GetUserStateByPhrase() = %v", err)
+	}
+
+	if p.ID != id || p.XP != 250 || p.Level != 2 {
+		t.Errorf("GetUserStateByPhrase() = id %s, xp %d, level %d; want %s, 250, 2", p.ID, p.XP, p.Level, id)
+	}
+
+	leaders, err := s.GetLeaderboard(10)
+	if err != nil {
+		t.Fatalf("GetLeaderboard() = %v", err)
+	}
+
+	if len(leaders) != 1 || leaders[0].UserName != "player" || leaders[0].XP != 250 {
+		t.Errorf("GetLeaderboard() = %+v, want only player with 250 xp", leaders)
+	}
+
+	if err := s.SetFlagDirect(id, "beta", true); err != nil {
+		t.Fatalf("SetFlagDirect() = %v", err)
+	}
+
+	flags, err := s.GetFlags(id)
+	if err != nil {
+		t.Fatalf("GetFlags() = %v", err)
+	}
+
+	if !flags["premium"] || !flags["beta"] {
+		t.Errorf("GetFlags() = %v, want premium and beta set", flags)
+	}
+
+	// Nothing was created in the default schema
+	exists, err := NewStore[any](db, "player_state").PlayerExists(id)
+	if err == nil && exists {
+		t.Error("player found in the default schema's player_state")
+	}
+}
+
+func TestInitPlayerDuplicates(t *testing.T) {
+	s := testStore[any](t)
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	err := s.InitPlayer(id, "other", "other phrase")
+	if !errors.Is(err, ErrPlayerExists) || errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with a duplicate id = %v, want ErrPlayerExists only", err)
+	}
+
+	err = s.InitPlayer(uuid.New(), "other", "phrase")
+	if !errors.Is(err, ErrPlayerExists) || !errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with a duplicate phrase = %v, want ErrPlayerExists and ErrPhraseTaken", err)
+	}
+
+	// Ids and phrases are unique within a season only
+	other := NewStore[any](s.db, s.tableName)
+	other.Season = "other"
+	if err := other.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Errorf("InitPlayer() in another season = %v, want nil", err)
+	}
+
+	err = other.InitPlayer(id, "player", "another phrase")
+	if !errors.Is(err, ErrPlayerExists) || errors.Is(err, ErrPhraseTaken) {
+		t.Errorf("InitPlayer() with a duplicate id in another season = %v, want ErrPlayerExists only", err)
+	}
+}
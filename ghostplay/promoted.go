@@ -0,0 +1,83 @@
+package ghostplay
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PromotedField copies a top-level extra data key into a dedicated column so
+// it can be indexed and filtered on efficiently, e.g., an integer "coins" or
+// a string "guild". The column is generated by Postgres from extra_data, so
+// every write keeps it in sync and ExtraData remains the source of truth.
+type PromotedField struct {
+	// Key is the top-level extra data key to promote.
+	Key string
+
+	// Column names the dedicated column; it must be a plain identifier.
+	Column string
+
+	// Type is the column's SQL type: TEXT, INTEGER, BIGINT, NUMERIC,
+	// DOUBLE PRECISION, or BOOLEAN.
+	Type string
+}
+
+// promotedTypes lists the column types a PromotedField may use. Each can be
+// cast from text immutably, as generated columns require.
+var promotedTypes = map[string]bool{
+	"TEXT":             true,
+	"INTEGER":          true,
+	"BIGINT":           true,
+	"NUMERIC":          true,
+	"DOUBLE PRECISION": true,
+	"BOOLEAN":          true,
+}
+
+// sqlType returns the field's column type, validated against promotedTypes.
+func (f PromotedField) sqlType() (string, error) {
+	typ := strings.ToUpper(strings.TrimSpace(f.Type))
+	if !promotedTypes[typ] {
+		return "", fmt.Errorf("%w: unsupported type %q for promoted field %q", ErrInvalidData, f.Type, f.Key)
+	}
+	return typ, nil
+}
+
+// column returns the field's column name quoted for use in SQL.
+func (f PromotedField) column() (string, error) {
+	if !isPlainIdentifier(f.Column) {
+		return "", fmt.Errorf("%w: invalid column %q for promoted field %q", ErrInvalidData, f.Column, f.Key)
+	}
+	return quoteIdentifier(strings.ToLower(f.Column)), nil
+}
+
+// definition returns the column definition InitPlayerStateTable adds.
+// The key is inlined as a string literal, since generated column expressions
+// can't take parameters.
+func (f PromotedField) definition(extraDataColumn string) (string, error) {
+	if f.Key == "" {
+		return "", fmt.Errorf("%w: promoted field key cannot be empty", ErrInvalidData)
+	}
+
+	column, err := f.column()
+	if err != nil {
+		return "", err
+	}
+
+	typ, err := f.sqlType()
+	if err != nil {
+		return "", err
+	}
+
+	key := "'" + strings.ReplaceAll(f.Key, "'", "''") + "'"
+	return fmt.Sprintf("%s %s GENERATED ALWAYS AS ((%s ->> %s)::%s) STORED",
+		column, typ, extraDataColumn, key, typ), nil
+}
+
+// promoted returns the Store's PromotedField for a top-level extra data key.
+func (s *Store[T]) promoted(key string) (PromotedField, bool) {
+	for _, f := range s.PromotedFields {
+		if f.Key == key {
+			return f, true
+		}
+	}
+	return PromotedField{}, false
+}
@@ -0,0 +1,175 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// RefreshRanks stores every player's leaderboard position in the rank
+// column, so getters can return it without counting on every read.
+func RefreshRanks(db *sql.DB, dbTableName string) (int64, error) {
+	return NewStore[any](db, dbTableName).RefreshRanks()
+}
+
+// RefreshRanks computes every live player's position on GetLeaderboard, by
+// XP and then the Store's Tiebreaker, with a window function and stores it in
+// the rank column, returning how many players' ranks changed. Soft-deleted
+// players are unranked. Players read back carry the stored value as Rank.
+//
+// Ranks are only accurate as of the last refresh: XP awarded since then isn't
+// reflected, and players created since have a Rank of zero. Each refresh
+// sorts the whole table and rewrites every row whose rank moved, so run it
+// periodically, e.g., from a scheduled job, rather than after every award.
+func (s *Store[T]) RefreshRanks() (int64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return 0, err
+	}
+
+	rank := s.expand(`
+		UPDATE {table} AS p
+		SET {rank} = ranked.position
+		FROM (
+			SELECT {id}, ROW_NUMBER() OVER (ORDER BY {xp} DESC, ` + tiebreak + `) AS position
+			FROM {table}
+			WHERE {live}
+		) AS ranked
+		WHERE p.{id} = ranked.{id} AND p.{season_id} = {season_value}
+			AND p.{rank} IS DISTINCT FROM ranked.position
+		`)
+
+	unrank := s.expand(`
+		UPDATE {table}
+		SET {rank} = NULL
+		WHERE NOT ({live}) AND {season} AND {rank} IS NOT NULL
+		`)
+
+	var changed int64
+	err = s.do("refresh_ranks", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		changed = 0
+		for _, query := range []string{rank, unrank} {
+			result, err := tx.ExecContext(ctx, query, s.seasonArgs()...)
+			if err != nil {
+				return fmt.Errorf("failed to refresh ranks: %w", err)
+			}
+
+			n, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("failed to read affected rows: %w", err)
+			}
+			changed += n
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit ranks: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	s.afterBulkSave(changed)
+	return changed, nil
+}
+
+// GetUserStatesWithRank fetches the players with the given UUIDs along with
+// their current leaderboard positions in one query.
+func GetUserStatesWithRank[T any](db *sql.DB, dbTableName string, ids []uuid.UUID) ([]*PlayerState[T], error) {
+	return NewStore[T](db, dbTableName).GetUserStatesWithRank(ids)
+}
+
+// GetUserStatesWithRank fetches the players with the given UUIDs in one
+// query, e.g., for a friends list, with Rank set to each player's current
+// position on GetLeaderboard rather than the value stored by RefreshRanks.
+// Positions are computed with a window function over every live player, by
+// XP and then the Store's Tiebreaker, so tied players get distinct, stable
+// positions, as RefreshRanks assigns them. Players are returned by position;
+// those that were not found are omitted.
+func (s *Store[T]) GetUserStatesWithRank(ids []uuid.UUID) ([]*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	for _, id := range ids {
+		if id == uuid.Nil {
+			return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+		}
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		WITH ranked AS (
+			SELECT {id} AS ranked_id, ROW_NUMBER() OVER (ORDER BY {xp} DESC, ` + tiebreak + `) AS ranked_position
+			FROM {table}
+			WHERE {live}
+		)
+		SELECT {player_columns}, ranked_position
+		FROM {table}
+		JOIN ranked ON {id} = ranked_id
+		WHERE {id} = ANY($1::uuid[]) AND {live}
+		ORDER BY ranked_position
+		`)
+
+	var players []*PlayerState[T]
+	err = s.do("get_by_ids_with_rank", func(ctx context.Context) error {
+		rows, err := s.reader().QueryContext(ctx, query, s.seasonArgs(uuidArray(ids))...)
+		if err != nil {
+			return fmt.Errorf("failed to query players: %w", err)
+		}
+		defer rows.Close()
+
+		players = nil
+		for rows.Next() {
+			var position uint64
+			state, err := s.scanPlayer(extraScanner{rows, []any{&position}})
+			if err != nil {
+				return fmt.Errorf("failed to scan player row: %w", err)
+			}
+			state.Rank = position
+			players = append(players, state)
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through player rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return players, nil
+}
+
+// extraScanner scans the columns selected after {player_columns} into
+// extra, so scanPlayer can read rows that carry more.
+type extraScanner struct {
+	row   rowScanner
+	extra []any
+}
+
+func (e extraScanner) Scan(dest ...any) error {
+	return e.row.Scan(append(dest, e.extra...)...)
+}
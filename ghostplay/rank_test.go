@@ -0,0 +1,136 @@
+package ghostplay
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// seasonStore returns a Store for season of the table s uses.
+func seasonStore(s *Store[any], season string) *Store[any] {
+	other := NewStore[any](s.db, s.tableName)
+	other.Season = season
+	return other
+}
+
+// awardXP saves the player with id, awarding xp.
+func awardXP(t *testing.T, s *Store[any], id uuid.UUID, xp uint64) {
+	t.Helper()
+
+	p, err := s.GetUserStateByID(id)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	if _, err := s.Save(p, xp); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+}
+
+// checkRank reports whether the player with id holds the stored rank want.
+func checkRank(t *testing.T, s *Store[any], id uuid.UUID, want uint64) {
+	t.Helper()
+
+	p, err := s.GetUserStateByID(id)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	if p.Rank != want {
+		t.Errorf("season %q: rank of %s = %d, want %d", s.Season, id, p.Rank, want)
+	}
+}
+
+func TestRefreshRanksKeepsOtherSeasons(t *testing.T) {
+	first := testStore[any](t)
+	first.Season = "first"
+	second := seasonStore(first, "second")
+
+	// The same player has a row in both seasons
+	id := uuid.New()
+	for _, s := range []*Store[any]{first, second} {
+		if err := s.InitPlayer(id, "player", "phrase"); err != nil {
+			t.Fatalf("InitPlayer() = %v", err)
+		}
+	}
+
+	leader := uuid.New()
+	if err := first.InitPlayer(leader, "leader", "leader phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+	awardXP(t, first, leader, 500)
+
+	if _, err := second.RefreshRanks(); err != nil {
+		t.Fatalf("RefreshRanks() = %v", err)
+	}
+
+	if _, err := first.RefreshRanks(); err != nil {
+		t.Fatalf("RefreshRanks() = %v", err)
+	}
+
+	checkRank(t, first, leader, 1)
+	checkRank(t, first, id, 2)
+	checkRank(t, second, id, 1)
+}
+
+func TestRefreshRanks(t *testing.T) {
+	s := testStore[any](t)
+	other := seasonStore(s, "other")
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, id := range ids {
+		if err := s.InitPlayer(id, "player", "phrase "+id.String()); err != nil {
+			t.Fatalf("InitPlayer() = %v", err)
+		}
+		awardXP(t, s, id, uint64(300-i*100))
+	}
+
+	// Players of another season, ranked before their XP changes
+	outside := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, id := range outside {
+		if err := other.InitPlayer(id, "outsider", "outside "+id.String()); err != nil {
+			t.Fatalf("InitPlayer() = %v", err)
+		}
+		awardXP(t, other, id, uint64(200-i*100))
+	}
+
+	if _, err := other.RefreshRanks(); err != nil {
+		t.Fatalf("RefreshRanks() = %v", err)
+	}
+	awardXP(t, other, outside[1], 500)
+
+	changed, err := s.RefreshRanks()
+	if err != nil {
+		t.Fatalf("RefreshRanks() = %v", err)
+	}
+
+	if changed != 3 {
+		t.Errorf("RefreshRanks() = %d, want 3", changed)
+	}
+
+	for i, id := range ids {
+		checkRank(t, s, id, uint64(i+1))
+	}
+
+	// Rows outside the ranked set keep their stored rank
+	checkRank(t, other, outside[0], 1)
+	checkRank(t, other, outside[1], 2)
+
+	// A soft-deleted player is unranked and the rest move up
+	if err := s.SoftDeletePlayer(ids[0]); err != nil {
+		t.Fatalf("SoftDeletePlayer() = %v", err)
+	}
+
+	if _, err := s.RefreshRanks(); err != nil {
+		t.Fatalf("RefreshRanks() = %v", err)
+	}
+
+	checkRank(t, s, ids[1], 1)
+	checkRank(t, s, ids[2], 2)
+	checkRank(t, other, outside[0], 1)
+	checkRank(t, other, outside[1], 2)
+
+	deleted := seasonStore(s, "")
+	deleted.IncludeDeleted = true
+	checkRank(t, deleted, ids[0], 0)
+}
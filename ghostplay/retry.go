@@ -0,0 +1,88 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how a Store retries operations that fail with a
+// transient connection error, such as during a database failover.
+// Only errors raised before a statement could reach the server are retried,
+// so retrying never applies a write twice. Errors like ErrPlayerNotFound or
+// a unique violation are returned immediately.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of tries, including the first.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry. It doubles after
+	// every further attempt, up to MaxBackoff when that is set.
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+
+	// RetrySerializationFailures also retries operations that fail with a
+	// serialization failure or a deadlock, which Postgres raises under the
+	// Store's IsolationLevel when concurrent transactions conflict. The
+	// failed transaction is rolled back, so the whole operation, reads
+	// included, is rerun from the start.
+	RetrySerializationFailures bool
+}
+
+// run calls fn until it succeeds, fails with a non-transient error, runs out
+// of attempts, or ctx is done. A nil policy calls fn exactly once.
+func (r *RetryPolicy) run(ctx context.Context, fn func(ctx context.Context) error) error {
+	err := fn(ctx)
+	if r == nil {
+		return err
+	}
+
+	backoff := r.InitialBackoff
+	for attempt := 1; attempt < r.MaxAttempts && r.retryable(err); attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if r.MaxBackoff > 0 && backoff > r.MaxBackoff {
+			backoff = r.MaxBackoff
+		}
+
+		err = fn(ctx)
+	}
+	return err
+}
+
+// retryable reports whether an operation that failed with err may be rerun.
+func (r *RetryPolicy) retryable(err error) bool {
+	if r.RetrySerializationFailures &&
+		(hasSQLState(err, sqlStateSerializationFailure) || hasSQLState(err, sqlStateDeadlockDetected)) {
+		return true
+	}
+	return isTransient(err)
+}
+
+// SQLSTATE codes for failures to establish a connection.
+const (
+	sqlStateUnableToConnect = "08001"
+	sqlStateConnRejected    = "08004"
+	sqlStateCannotConnect   = "57P03"
+)
+
+// isTransient reports whether err is a connection failure that is safe to retry.
+func isTransient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		hasSQLState(err, sqlStateUnableToConnect) ||
+		hasSQLState(err, sqlStateConnRejected) ||
+		hasSQLState(err, sqlStateCannotConnect)
+}
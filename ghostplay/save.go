@@ -0,0 +1,249 @@
+package ghostplay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SaveResult describes the XP and level of a player before and after a save.
+// For a newly created player, the previous values are the Store's starting values.
+// DiscardedXP is the part of the award dropped by the Store's GateLevel.
+type SaveResult struct {
+	PreviousXP    uint64 `json:"previous_xp"`
+	PreviousLevel uint32 `json:"previous_level"`
+	XP            uint64 `json:"xp"`
+	Level         uint32 `json:"level"`
+	Created       bool   `json:"created"`
+	DiscardedXP   uint64 `json:"discarded_xp"`
+}
+
+// LeveledUp reports whether the save raised the player's level.
+func (r SaveResult) LeveledUp() bool {
+	return r.Level > r.PreviousLevel
+}
+
+// PreviewSave computes the result Save would produce for the given XP award
+// against the player's current stored state, without writing anything.
+// p is not modified. A player with a nil ID is previewed as a new player.
+func (s *Store[T]) PreviewSave(p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	if s.db == nil {
+		return SaveResult{}, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if p == nil {
+		return SaveResult{}, fmt.Errorf("%w: player cannot be nil", ErrInvalidData)
+	}
+
+	if p.ID == uuid.Nil {
+		return s.nextState(nil, p, xpIncrease)
+	}
+
+	var result SaveResult
+	err := s.do("preview_save", func(ctx context.Context) error {
+		// Match Save by reading from the primary
+		player, err := s.getUserStateByID(ctx, s.db, p.ID)
+		if err != nil && !errors.Is(err, ErrPlayerNotFound) {
+			return fmt.Errorf("failed to fetch player state: %w", err)
+		}
+
+		result, err = s.nextState(player, p, xpIncrease)
+		return err
+	})
+	return result, err
+}
+
+// SaveOption configures a call to SaveWithOptions.
+type SaveOption func(*saveOptions)
+
+// AwardXP awards xp to the player, as Save's xpIncrease does. The award is
+// subject to the Store's MinAwardInterval and GateLevel. It isn't named WithXP,
+// which is already the PlayerFields value selecting a player's XP.
+func AwardXP(xp uint64) SaveOption {
+	return func(o *saveOptions) {
+		o.xpIncrease = xp
+	}
+}
+
+// WithoutTimestamp leaves an existing player's last update time, and who
+// made it, unchanged, as SaveQuiet does. A player the save creates still
+// records the creation time.
+func WithoutTimestamp() SaveOption {
+	return func(o *saveOptions) {
+		o.keepLastUpdated = true
+	}
+}
+
+// WithLevel sets the player's level to exactly level rather than deriving it
+// from their XP with the LevelCurve, overriding AutoLevel and GateLevel. Any
+// XP award is still made.
+func WithLevel(level uint32) SaveOption {
+	return func(o *saveOptions) {
+		o.level = &level
+	}
+}
+
+// WithClock makes the save take the time it records as the player's last
+// update from clock instead of the Store's Clock. The MinAwardInterval is
+// measured from the same time.
+func WithClock(clock func() time.Time) SaveOption {
+	return func(o *saveOptions) {
+		o.clock = clock
+	}
+}
+
+// DryRun computes the result of the save without writing anything or
+// modifying the player, as PreviewSave does. Only AwardXP and WithLevel are
+// taken into account; the other options and the save's validation are skipped.
+func DryRun() SaveOption {
+	return func(o *saveOptions) {
+		o.dryRun = true
+	}
+}
+
+// ExpectVersion writes the save only if the stored player's last update
+// time still equals version, typically the LastUpdated of the state the
+// caller read, so concurrent changes aren't overwritten. ErrVersionMismatch
+// is returned, and the player left unchanged, if it doesn't; since
+// WithoutTimestamp saves don't change the last update time, they aren't
+// detected. ErrPlayerNotFound is returned instead of creating a player.
+func ExpectVersion(version time.Time) SaveOption {
+	return func(o *saveOptions) {
+		o.expectVersion = &version
+	}
+}
+
+// SaveWithOptions saves the player as configured by opts, applied in order,
+// so a later AwardXP replaces an earlier one. With no options it behaves as
+// Save with no XP award; Save itself is SaveWithOptions with AwardXP.
+func (s *Store[T]) SaveWithOptions(p *PlayerState[T], opts ...SaveOption) (SaveResult, error) {
+	var o saveOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.dryRun {
+		result, err := s.PreviewSave(p, o.xpIncrease)
+		if err == nil && o.level != nil {
+			result.Level = *o.level
+		}
+		return result, err
+	}
+	return s.saveWith(s.ctx, nil, p, o.xpIncrease, o)
+}
+
+// SaveQuiet is like Save but leaves an existing player's last update time
+// unchanged, for maintenance jobs whose writes shouldn't count as activity.
+// A player it creates still records the creation time.
+//
+// Code using LastUpdated to detect concurrent changes won't see quiet saves,
+// and a quiet save doesn't restart the MinAwardInterval wait, though its own
+// XP awards are still subject to it.
+func (s *Store[T]) SaveQuiet(p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	return s.saveWith(s.ctx, nil, p, xpIncrease, saveOptions{keepLastUpdated: true})
+}
+
+// SaveDelta is like Save but takes a signed XP change. A positive or zero
+// delta behaves exactly as Save. A negative delta takes XP away, clamping the
+// player's XP at zero; when AutoLevel is enabled the player is then demoted to
+// the highest level their remaining XP meets on the LevelCurve, possibly
+// several levels at once, but never below the Store's StartingLevel. XP
+// removals are recorded in the audit table as negative changes and are not
+// subject to MinAwardInterval. A player created by SaveDelta starts from
+// StartingXP, so a negative delta leaves them with less, or zero.
+func (s *Store[T]) SaveDelta(p *PlayerState[T], delta int64) (SaveResult, error) {
+	if delta >= 0 {
+		return s.saveWith(s.ctx, nil, p, uint64(delta), saveOptions{})
+	}
+
+	// Negate without overflowing on math.MinInt64
+	return s.saveWith(s.ctx, nil, p, 0, saveOptions{xpDecrease: uint64(-(delta + 1)) + 1})
+}
+
+// SaveContext is Save run on tx, typically an *sql.Tx, and bounded by ctx.
+// The player is read within tx, so writes made earlier in the same
+// uncommitted transaction are seen, and their row stays locked until the
+// caller commits or rolls back; audit entries and notifications are written
+// in tx too. The Store's own connections are not used.
+func (s *Store[T]) SaveContext(ctx context.Context, tx Querier, p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	if tx == nil {
+		return SaveResult{}, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+	return s.saveWith(ctx, tx, p, xpIncrease, saveOptions{})
+}
+
+// SaveExact writes the player's Level and XP exactly as given, along with its
+// flags and extra data, for admin and anti-cheat tooling.
+//
+// Unlike Save, no XP is accumulated and the level curve is deliberately
+// skipped, so the stored level need not match the stored XP. The change is
+// not recorded in the audit table. SaveExact never creates a player;
+// ErrPlayerNotFound is returned if no player has p's UUID.
+func (s *Store[T]) SaveExact(p *PlayerState[T]) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if p == nil {
+		return fmt.Errorf("%w: player cannot be nil", ErrInvalidData)
+	}
+
+	if p.ID == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if err := s.checkFlags(p.Flags); err != nil {
+		return err
+	}
+
+	if s.Validate != nil {
+		if err := s.Validate(p.ExtraData); err != nil {
+			return fmt.Errorf("%w: extra data: %w", ErrInvalidData, err)
+		}
+	}
+
+	extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+	if err != nil {
+		return err
+	}
+
+	flags := p.Flags
+	if flags == nil {
+		flags = make(map[string]bool)
+	}
+
+	flagsJSON, err := marshalFlags(s.codec(), flags)
+	if err != nil {
+		return err
+	}
+
+	query := s.expand(`
+		UPDATE {table}
+		SET {level} = $1,
+			{xp} = $2,
+			{extra_data} = $3,
+			{flags} = $4,
+			{last_updated} = $5,
+			{updated_by} = $7
+		WHERE {id} = $6 AND {live}
+		`)
+
+	lastUpdated := s.now()
+	err = s.do("save_exact", func(ctx context.Context) error {
+		result, err := s.db.ExecContext(ctx, query, s.seasonArgs(p.Level, p.XP, extraData, flagsJSON, lastUpdated, p.ID, actor(p.UpdatedBy))...)
+		if err != nil {
+			return fmt.Errorf("failed to update player data: %w", err)
+		}
+		return requireRowAffected(result)
+	})
+	if err != nil {
+		return err
+	}
+
+	p.LastUpdated = lastUpdated
+	s.afterSave(p.ID, SaveResult{XP: p.XP, Level: p.Level})
+	return nil
+}
@@ -0,0 +1,278 @@
+package ghostplay
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestLockOnSaveKeepsConcurrentAwards(t *testing.T) {
+	s := testStore[any](t)
+	s.LockOnSave = true
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	const awards = 20
+	var wg sync.WaitGroup
+	for i := 0; i < awards; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p, err := s.GetUserStateByID(id)
+			if err != nil {
+				t.Errorf("GetUserStateByID() = %v", err)
+				return
+			}
+
+			if _, err := s.Save(p, 10); err != nil {
+				t.Errorf("Save() = %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	xp, _, err := s.GetXPAndLevel(id)
+	if err != nil {
+		t.Fatalf("GetXPAndLevel() = %v", err)
+	}
+
+	if xp != awards*10 {
+		t.Errorf("xp = %d, want %d", xp, awards*10)
+	}
+}
+
+func TestGateLevel(t *testing.T) {
+	tests := []struct {
+		name          string
+		award         uint64
+		wantXP        uint64
+		wantLevel     uint32
+		wantDiscarded uint64
+	}{
+		{"below the cap", 598, 598, 3, 0},
+		{"at the cap", 599, 599, 3, 0},
+		{"one over the cap", 600, 599, 3, 1},
+		{"far over the cap", 10_000, 599, 3, 9_401},
+	}
+
+	for _, tt := range tests {
+		s := NewStore[any](nil, "players")
+		s.GateLevel = 3
+		m := NewMemoryStore(s)
+
+		p := NewPlayerState[any]("player", "phrase")
+		result, err := m.Save(p, tt.award)
+		if err != nil {
+			t.Fatalf("%s: Save() = %v", tt.name, err)
+		}
+
+		if result.XP != tt.wantXP || result.Level != tt.wantLevel || result.DiscardedXP != tt.wantDiscarded {
+			t.Errorf("%s: Save() = xp %d, level %d, discarded %d; want %d, %d, %d", tt.name,
+				result.XP, result.Level, result.DiscardedXP, tt.wantXP, tt.wantLevel, tt.wantDiscarded)
+		}
+	}
+}
+
+func TestGateLevelKeepsPlayersBeyondIt(t *testing.T) {
+	s := NewStore[any](nil, "players")
+	s.GateLevel = 3
+
+	// A player already past the gate keeps their XP and level
+	stored := &PlayerState[any]{XP: 1_000, Level: 6}
+	result, err := s.nextState(stored, stored, 50)
+	if err != nil {
+		t.Fatalf("nextState() = %v", err)
+	}
+
+	if result.XP != 1_000 || result.Level != 6 || result.DiscardedXP != 50 {
+		t.Errorf("nextState() = %+v, want xp 1000, level 6, 50 discarded", result)
+	}
+
+	if got := s.gateXP(); got != 599 {
+		t.Errorf("gateXP() = %d, want 599", got)
+	}
+
+	s.GateLevel = math.MaxUint32
+	if got := s.gateXP(); got != math.MaxUint64 {
+		t.Errorf("gateXP() without a reachable cap = %d, want MaxUint64", got)
+	}
+}
+
+func TestSaveXPOverflow(t *testing.T) {
+	m := NewMemoryStore[any](nil)
+	p := NewPlayerState[any]("player", "phrase")
+	if _, err := m.Save(p, math.MaxInt64); err != nil {
+		t.Fatalf("Save() up to the maximum = %v", err)
+	}
+
+	if _, err := m.Save(p, 1); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("Save() past the maximum = %v, want ErrInvalidData", err)
+	}
+
+	stored, err := m.GetUserStateByID(p.ID)
+	if err != nil {
+		t.Fatalf("GetUserStateByID() = %v", err)
+	}
+
+	if stored.XP != math.MaxInt64 {
+		t.Errorf("xp after a rejected Save = %d, want %d", stored.XP, uint64(math.MaxInt64))
+	}
+}
+
+// fakePlayer answers the queries of a Save of a single stored player, keeping
+// the XP and level written by its updates.
+func fakePlayer(id uuid.UUID, xp uint64, level uint32) fakeHandler {
+	lastUpdated := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	return func(query string, args []driver.NamedValue) ([][]driver.Value, error) {
+		if strings.Contains(query, "UPDATE") {
+			level = args[0].Value.(uint32)
+			xp = args[1].Value.(uint64)
+			if updated := args[4].Value.(sql.NullTime); updated.Valid {
+				lastUpdated = updated.Time
+			}
+			return [][]driver.Value{{lastUpdated, int64(level), int64(xp)}}, nil
+		}
+
+		row := []driver.Value{
+			id.String(), "player", "phrase", int64(level), int64(xp), lastUpdated,
+			[]byte(`{}`), []byte(`{}`), nil, nil, nil,
+		}
+		return [][]driver.Value{row}, nil
+	}
+}
+
+func TestSaveDeltaNegative(t *testing.T) {
+	tests := []struct {
+		name      string
+		xp        uint64
+		level     uint32
+		delta     int64
+		wantXP    uint64
+		wantLevel uint32
+	}{
+		{"within the level", 450, 3, -50, 400, 3},
+		{"one level down", 450, 3, -51, 399, 2},
+		{"several levels down", 1_250, 7, -1_000, 250, 2},
+		{"clamped at zero", 450, 3, -10_000, 0, 1},
+		{"minimum int64", 450, 3, math.MinInt64, 0, 1},
+	}
+
+	for _, tt := range tests {
+		id := uuid.New()
+		s := NewStore[any](fakeDB(t, fakePlayer(id, tt.xp, tt.level)), "players")
+
+		p := &PlayerState[any]{ID: id, UserName: "player", Phrase: "phrase", XP: tt.xp, Level: tt.level}
+		result, err := s.SaveDelta(p, tt.delta)
+		if err != nil {
+			t.Fatalf("%s: SaveDelta() = %v", tt.name, err)
+		}
+
+		if result.XP != tt.wantXP || result.Level != tt.wantLevel {
+			t.Errorf("%s: SaveDelta() = xp %d, level %d; want %d, %d", tt.name, result.XP, result.Level, tt.wantXP, tt.wantLevel)
+		}
+
+		if p.XP != tt.wantXP || p.Level != tt.wantLevel {
+			t.Errorf("%s: player holds xp %d, level %d; want %d, %d", tt.name, p.XP, p.Level, tt.wantXP, tt.wantLevel)
+		}
+	}
+}
+
+func TestSaveDeltaKeepsStartingLevel(t *testing.T) {
+	id := uuid.New()
+	s := NewStore[any](fakeDB(t, fakePlayer(id, 900, 5)), "players")
+	s.StartingLevel = 3
+
+	p := &PlayerState[any]{ID: id, UserName: "player", Phrase: "phrase", XP: 900, Level: 5}
+	result, err := s.SaveDelta(p, -900)
+	if err != nil {
+		t.Fatalf("SaveDelta() = %v", err)
+	}
+
+	if result.XP != 0 || result.Level != 3 {
+		t.Errorf("SaveDelta() = xp %d, level %d; want 0, 3", result.XP, result.Level)
+	}
+}
+
+func TestClockSetsLastUpdated(t *testing.T) {
+	frozen := time.Date(2025, 6, 7, 8, 9, 10, 0, time.UTC)
+	clock := func() time.Time { return frozen }
+
+	// Created and updated players both take the Store's clock
+	s := NewStore[any](nil, "players")
+	s.Clock = clock
+	m := NewMemoryStore(s)
+
+	p := NewPlayerState[any]("player", "phrase")
+	for i := 0; i < 2; i++ {
+		if _, err := m.Save(p, 10); err != nil {
+			t.Fatalf("Save() = %v", err)
+		}
+
+		stored, err := m.GetUserStateByID(p.ID)
+		if err != nil {
+			t.Fatalf("GetUserStateByID() = %v", err)
+		}
+
+		if !p.LastUpdated.Equal(frozen) || !stored.LastUpdated.Equal(frozen) {
+			t.Errorf("save %d: LastUpdated = %v, stored %v; want %v", i+1, p.LastUpdated, stored.LastUpdated, frozen)
+		}
+	}
+
+	// The written time is the one the Store's Clock returns
+	id := uuid.New()
+	s = NewStore[any](fakeDB(t, fakePlayer(id, 0, 1)), "players")
+	s.Clock = clock
+
+	p = &PlayerState[any]{ID: id, UserName: "player", Phrase: "phrase", Level: 1}
+	if _, err := s.Save(p, 10); err != nil {
+		t.Fatalf("Save() = %v", err)
+	}
+
+	if !p.LastUpdated.Equal(frozen) {
+		t.Errorf("LastUpdated = %v, want %v", p.LastUpdated, frozen)
+	}
+
+	// WithClock overrides it for one save
+	later := frozen.Add(time.Hour)
+	if _, err := s.SaveWithOptions(p, WithClock(func() time.Time { return later })); err != nil {
+		t.Fatalf("SaveWithOptions() = %v", err)
+	}
+
+	if !p.LastUpdated.Equal(later) {
+		t.Errorf("LastUpdated with WithClock = %v, want %v", p.LastUpdated, later)
+	}
+}
+
+func TestClockSetsStoredLastUpdated(t *testing.T) {
+	s := testStore[any](t)
+	frozen := time.Date(2025, 6, 7, 8, 9, 10, 0, time.UTC)
+	s.Clock = func() time.Time { return frozen }
+
+	p := NewPlayerState[any]("player", "phrase")
+	for i := 0; i < 2; i++ {
+		if _, err := s.Save(p, 10); err != nil {
+			t.Fatalf("Save() = %v", err)
+		}
+
+		stored, err := s.GetUserStateByID(p.ID)
+		if err != nil {
+			t.Fatalf("GetUserStateByID() = %v", err)
+		}
+
+		if !stored.LastUpdated.Equal(frozen) {
+			t.Errorf("save %d: stored LastUpdated = %v, want %v", i+1, stored.LastUpdated, frozen)
+		}
+	}
+}
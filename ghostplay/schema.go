@@ -0,0 +1,128 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// VerifySchema checks that an existing player state table has the columns
+// InitPlayerStateTable creates, with the expected types.
+func VerifySchema(db *sql.DB, dbTableName string) error {
+	return NewStore[any](db, dbTableName).VerifySchema()
+}
+
+// VerifySchema checks that the player state table has every column the Store
+// reads and writes, under its Columns names and PromotedFields, with the
+// types InitPlayerStateTable creates, e.g., as a startup check against
+// manual changes. It doesn't modify the table. ErrSchemaMismatch is returned
+// listing every missing or mistyped column, and ErrTableNotInitialized if
+// the table doesn't exist. Extra columns are allowed.
+func (s *Store[T]) VerifySchema() error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if s.tableErr != nil {
+		return s.tableErr
+	}
+
+	parts, err := splitTableName(s.tableName)
+	if err != nil {
+		return err
+	}
+
+	// An unqualified table is looked up in the current schema
+	table := parts[len(parts)-1]
+	var schema sql.NullString
+	if len(parts) > 1 {
+		schema = sql.NullString{String: parts[len(parts)-2], Valid: true}
+	}
+
+	c := s.Columns
+	expected := []struct{ column, dataType string }{
+		{c.ID, "uuid"},
+		{c.SeasonID, "text"},
+		{c.Phrase, "character varying"},
+		{c.UserName, "character varying"},
+		{c.Level, "integer"},
+		{c.XP, "bigint"},
+		{c.LastUpdated, "timestamp with time zone"},
+		{c.Flags, "jsonb"},
+		{c.ExtraData, "jsonb"},
+		{c.DeletedAt, "timestamp with time zone"},
+		{c.UpdatedBy, "text"},
+		{c.Rank, "bigint"},
+		{c.LastDecayed, "timestamp with time zone"},
+	}
+
+	for _, f := range s.PromotedFields {
+		typ, err := f.sqlType()
+		if err != nil {
+			return err
+		}
+
+		if !isPlainIdentifier(f.Column) {
+			return fmt.Errorf("%w: invalid column %q for promoted field %q", ErrInvalidData, f.Column, f.Key)
+		}
+		expected = append(expected, struct{ column, dataType string }{strings.ToLower(f.Column), strings.ToLower(typ)})
+	}
+
+	query := `
+		SELECT column_name, data_type
+		FROM information_schema.columns
+		WHERE table_schema = COALESCE($1::text, current_schema()) AND table_name = $2
+		`
+
+	columns := make(map[string]string)
+	err = s.do("verify_schema", func(ctx context.Context) error {
+		rows, err := s.db.QueryContext(ctx, query, schema, table)
+		if err != nil {
+			return fmt.Errorf("failed to query table columns: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var name, dataType string
+			if err := rows.Scan(&name, &dataType); err != nil {
+				return fmt.Errorf("failed to scan column row: %w", err)
+			}
+			columns[name] = dataType
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through column rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(columns) == 0 {
+		return fmt.Errorf("%w: table %s does not exist", ErrTableNotInitialized, s.table)
+	}
+
+	var problems []string
+	for _, want := range expected {
+		// Unquoted column names are folded to lower case by Postgres
+		name, err := columnName(want.column)
+		if err != nil {
+			return err
+		}
+
+		got, ok := columns[name]
+		switch {
+		case !ok:
+			problems = append(problems, fmt.Sprintf("column %q is missing", want.column))
+		case got != want.dataType:
+			problems = append(problems, fmt.Sprintf("column %q has type %s, want %s", want.column, got, want.dataType))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: table %s: %s", ErrSchemaMismatch, s.table, strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package ghostplay
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+func TestUndefinedTable(t *testing.T) {
+	db := fakeDB(t, func(string, []driver.NamedValue) ([][]driver.Value, error) {
+		return nil, &pq.Error{Code: sqlStateUndefinedTable, Message: `relation "players" does not exist`}
+	})
+	s := NewStore[any](db, "players")
+	id := uuid.New()
+
+	calls := map[string]func() error{
+		"GetUserStateByID": func() error {
+			_, err := s.GetUserStateByID(id)
+			return err
+		},
+		"Save": func() error {
+			_, err := s.Save(NewPlayerState[any]("player", "phrase"), 10)
+			return err
+		},
+		"GetLeaderboard": func() error {
+			_, err := s.GetLeaderboard(10)
+			return err
+		},
+		"SetFlagDirect": func() error {
+			return s.SetFlagDirect(id, "premium", true)
+		},
+	}
+
+	for name, call := range calls {
+		err := call()
+		if !errors.Is(err, ErrTableNotInitialized) {
+			t.Errorf("%s = %v, want ErrTableNotInitialized", name, err)
+		}
+
+		var pqErr *pq.Error
+		if !errors.As(err, &pqErr) {
+			t.Errorf("%s = %v, want it to keep the driver error", name, err)
+		}
+	}
+}
+
+func TestVerifySchemaMissingTable(t *testing.T) {
+	s := NewStore[any](fakeDB(t, noRows), "players")
+
+	if err := s.VerifySchema(); !errors.Is(err, ErrTableNotInitialized) {
+		t.Errorf("VerifySchema() = %v, want ErrTableNotInitialized", err)
+	}
+}
+
+func TestNonexistentTable(t *testing.T) {
+	db := testDB(t)
+	s := NewStore[any](db, "ghostplay_test_missing_"+uuid.New().String()[:8])
+
+	if _, err := s.GetUserStateByID(uuid.New()); !errors.Is(err, ErrTableNotInitialized) {
+		t.Errorf("GetUserStateByID() = %v, want ErrTableNotInitialized", err)
+	}
+
+	if err := s.VerifySchema(); !errors.Is(err, ErrTableNotInitialized) {
+		t.Errorf("VerifySchema() = %v, want ErrTableNotInitialized", err)
+	}
+}
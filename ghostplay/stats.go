@@ -0,0 +1,145 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+)
+
+// Stats holds aggregate figures across every player in a table.
+// All fields are zero when the table has no players.
+type Stats struct {
+	TotalPlayers int64   `json:"total_players"`
+	AverageXP    float64 `json:"average_xp"`
+	MaxXP        uint64  `json:"max_xp"`
+	MaxLevel     uint32  `json:"max_level"`
+	AverageLevel float64 `json:"average_level"`
+}
+
+// GetStats returns aggregate player statistics computed in a single query.
+func GetStats(db *sql.DB, dbTableName string) (Stats, error) {
+	return NewStore[any](db, dbTableName).GetStats()
+}
+
+// GetStats returns aggregate player statistics computed in a single query.
+func (s *Store[T]) GetStats() (Stats, error) {
+	if s.db == nil {
+		return Stats{}, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	// The aggregates are NULL on an empty table so fall back to zero.
+	query := s.expand(`
+		SELECT COUNT(*),
+			COALESCE(AVG({xp}), 0)::float8,
+			COALESCE(MAX({xp}), 0),
+			COALESCE(MAX({level}), 0),
+			COALESCE(AVG({level}), 0)::float8
+		FROM {table}
+		WHERE {live}
+		`)
+
+	var stats Stats
+	err := s.do("stats", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs()...).Scan(
+			&stats.TotalPlayers,
+			&stats.AverageXP,
+			&stats.MaxXP,
+			&stats.MaxLevel,
+			&stats.AverageLevel,
+		)
+	})
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to query player stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+// LevelHistogram returns the number of players at each level.
+func LevelHistogram(db *sql.DB, dbTableName string) (map[uint32]int64, error) {
+	return NewStore[any](db, dbTableName).LevelHistogram()
+}
+
+// LevelHistogram returns the number of players at each level, computed in a
+// single query. Levels nobody holds are absent from the map, and an empty
+// table yields an empty map. Being a map, it has no order; sort its keys to
+// walk the levels in order.
+func (s *Store[T]) LevelHistogram() (map[uint32]int64, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	query := s.expand(`
+		SELECT {level}, COUNT(*)
+		FROM {table}
+		WHERE {live}
+		GROUP BY {level}
+		`)
+
+	var histogram map[uint32]int64
+	err := s.do("level_histogram", func(ctx context.Context) error {
+		rows, err := s.reader().QueryContext(ctx, query, s.seasonArgs()...)
+		if err != nil {
+			return fmt.Errorf("failed to query level histogram: %w", err)
+		}
+		defer rows.Close()
+
+		histogram = make(map[uint32]int64)
+		for rows.Next() {
+			var level uint32
+			var count int64
+			if err := rows.Scan(&level, &count); err != nil {
+				return fmt.Errorf("failed to scan level histogram row: %w", err)
+			}
+			histogram[level] = count
+		}
+
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through level histogram rows: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return histogram, nil
+}
+
+// SumXP returns the total XP held by every player.
+func SumXP(db *sql.DB, dbTableName string) (uint64, error) {
+	return NewStore[any](db, dbTableName).SumXP()
+}
+
+// SumXP returns the total XP held by every player, computed in a single
+// query; it is zero when the table has no players. Postgres sums the XP
+// exactly, but a total beyond math.MaxUint64 is capped at math.MaxUint64.
+func (s *Store[T]) SumXP() (uint64, error) {
+	if s.db == nil {
+		return 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	// SUM is NULL on an empty table and numeric otherwise, so cap it and
+	// read it as text
+	query := s.expand(`
+		SELECT LEAST(COALESCE(SUM({xp}), 0), 18446744073709551615)::text
+		FROM {table}
+		WHERE {live}
+		`)
+
+	var sum string
+	err := s.do("sum_xp", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs()...).Scan(&sum)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to query total xp: %w", err)
+	}
+
+	total, err := strconv.ParseUint(sum, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse total xp %q: %w", sum, err)
+	}
+
+	return total, nil
+}
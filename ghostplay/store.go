@@ -0,0 +1,1515 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Store binds a database connection and a player state table to the
+// configuration used when creating and updating players.
+// Configuration fields must be set before the Store is first used.
+type Store[T any] struct {
+	// StartingLevel is the level newly created players begin at.
+	StartingLevel uint32
+
+	// StartingXP is the XP newly created players begin with before any award.
+	// Level-ups are measured against the player's total XP, so a StartingXP
+	// consistent with StartingLevel (e.g., 800 for level 5) keeps progression
+	// in line with players who levelled up naturally.
+	StartingXP uint64
+
+	// AutoLevel makes Save and DecayInactivePlayers move a player's level
+	// along the level curve as their XP changes. Save raises the level to
+	// the highest one whose cumulative requirement the player's total XP
+	// meets. It defaults to true; when false, XP is a pure counter, the curve
+	// is never consulted, and levels only change when set explicitly.
+	AutoLevel bool
+
+	// LevelCurve defines the total XP required for each level.
+	// DefaultLevelCurve is used when nil. The curve is checked with Validate
+	// on the Store's first operation, and every operation fails with its
+	// error if it is invalid.
+	LevelCurve LevelCurve
+
+	// GateLevel, when non-zero, is the highest level XP awards can reach
+	// until it is raised, e.g., once the player completes a gate quest.
+	// Save and AwardXPAndSetFlags cap XP just below the LevelCurve
+	// requirement for the level after GateLevel, discarding the excess rather
+	// than banking it, and never raise the level past GateLevel. Players
+	// already beyond the gate keep their XP and level. There is no gate when
+	// zero.
+	GateLevel uint32
+
+	// Season selects the season whose player rows the Store reads and
+	// writes, for games that keep separate XP and levels per season while
+	// players keep their identity. A player has one row per season, keyed
+	// by id and season. The empty default season is the only one used by
+	// single-season games. Added phrases are shared by every season.
+	//
+	// Tables created before seasons existed key players by id alone, and
+	// their phrases are unique across seasons; widen the primary key to
+	// (id, season_id) and the phrase constraint to (phrase, season_id) to
+	// keep more than one season in them.
+	Season string
+
+	// IncludeDeleted makes getters, leaderboards, and stats return soft-deleted
+	// players as well. It is intended for admin tooling.
+	IncludeDeleted bool
+
+	// AuditTable names a table created with InitAuditTable. When set, every XP
+	// award made by Save is recorded there in the same transaction as the
+	// update. Auditing is disabled when empty.
+	AuditTable string
+
+	// Columns maps player state to the table's column names.
+	// It defaults to DefaultColumns.
+	Columns Columns
+
+	// Tiebreaker orders players with equal XP on leaderboards.
+	// It defaults to TiebreakLevelThenOldest.
+	Tiebreaker Tiebreaker
+
+	// CaseInsensitivePhrases lower-cases phrases before they are stored or
+	// looked up, so "DragonFly" and "dragonfly" are the same phrase. It must
+	// be set consistently on every Store sharing a table. Enabling it on an
+	// existing table requires lower-casing the stored phrases first, merging
+	// players whose phrases differ only in case, since the lower-cased
+	// phrases must remain unique.
+	CaseInsensitivePhrases bool
+
+	// PhraseTable names a table created with InitPhraseTable holding extra
+	// phrases added with AddPhrase, e.g., one per device. When set,
+	// GetUserStateByPhrase and PhraseExists search it as well as the player
+	// table's own phrase column.
+	PhraseTable string
+
+	// MaxUserNameLength and MaxPhraseLength are the longest user name and
+	// phrase, in characters, that InitPlayer, InitPlayers, Save, and
+	// ImportPlayer accept, matching the VARCHAR(255) columns by default.
+	// Lower or raise them to match a custom schema; zero disables the check.
+	// MaxPhraseLength isn't applied when a PhraseHasher is set, since the
+	// stored hash has its own fixed length.
+	MaxUserNameLength int
+	MaxPhraseLength   int
+
+	// MinAwardInterval, when positive, makes Save reject XP awards to an
+	// existing player with ErrAwardTooSoon until this long has passed since
+	// the player was last updated. The check is part of the update itself,
+	// so concurrent awards can't both slip through. Saves that award no XP
+	// are never rejected. It is disabled by default.
+	MinAwardInterval time.Duration
+
+	// LockOnSave makes Save run in a transaction that locks the player's row
+	// with SELECT ... FOR UPDATE before computing the new XP and level, so
+	// concurrent awards to the same player are serialized and none are lost.
+	// Without it, Save reads and then writes, and an award made between the
+	// two is overwritten. Concurrent first saves of a new player still race
+	// to create it, and all but one fail.
+	LockOnSave bool
+
+	// PhraseHasher, when set, hashes phrases before they are stored and
+	// before lookups, so players read back from the Store carry the hashed
+	// phrase. Phrases are stored in plaintext by default. Switching an
+	// existing table to hashing requires rehashing its stored phrases.
+	PhraseHasher PhraseHasher
+
+	// QueryTimeout, when positive, bounds each attempt at a Store operation;
+	// an attempt still running after it is canceled and fails with
+	// context.DeadlineExceeded. There is no timeout by default. It can also
+	// be set with SetQueryTimeout.
+	QueryTimeout time.Duration
+
+	// Retry, when set, retries operations that fail with a transient
+	// connection error using exponential backoff. Retries are disabled by default.
+	Retry *RetryPolicy
+
+	// IsolationLevel is used for the transactions the Store begins itself,
+	// such as Save with LockOnSave, audited saves, TransferXP, and
+	// MergePlayers; transactions passed in by the caller keep their own.
+	// It defaults to the driver's default, READ COMMITTED on Postgres.
+	// Under sql.LevelSerializable, concurrent awards may fail with a
+	// serialization failure instead of waiting on each other; set the
+	// Retry policy's RetrySerializationFailures to rerun them.
+	IsolationLevel sql.IsolationLevel
+
+	// NotifyChannel, when set, makes Save and TransferXP send a PlayerChange
+	// as a JSON payload on this Postgres channel with pg_notify, in the same
+	// transaction as the write. Use Listen to receive them. Notifications are
+	// disabled when empty.
+	NotifyChannel string
+
+	// Clock, when set, supplies the time written as a player's last update,
+	// e.g., a frozen clock in tests. It defaults to time.Now.
+	Clock func() time.Time
+
+	// Observer, when set, is notified after each database operation.
+	Observer QueryObserver
+
+	// StrictExtraData makes getters fail when stored extra data holds a key
+	// that T's structs don't declare, such as a field since removed from T,
+	// instead of silently ignoring it. Fields of T missing from the stored
+	// data keep their zero values in either mode. Maps accept any key.
+	StrictExtraData bool
+
+	// Codec, when set, encodes and decodes the flags and extra data that
+	// Save, the other writes, and the getters store and read, e.g., a faster
+	// JSON library. It defaults to JSONCodec. Under StrictExtraData, extra
+	// data is still decoded with encoding/json, which can reject unknown keys.
+	// Exports, dumps, and notification payloads always use encoding/json.
+	Codec Codec
+
+	// DefaultExtraData, when set, supplies the ExtraData of newly created
+	// players, e.g., a starting inventory. InitPlayer and InitPlayers store
+	// it, and Save uses it when creating a player whose ExtraData is the zero
+	// value; it is called for each such player so they never share maps or
+	// slices. Existing players are never affected.
+	DefaultExtraData func() T
+
+	// AllowedFlags, when non-empty, lists the only flag keys that may be
+	// written. Save, SaveExact, ImportPlayer, and the flag-setting helpers
+	// reject any other key with ErrInvalidData before writing, catching typos
+	// like "tutorual_done". Flags already stored are left alone. Any key is
+	// allowed when empty.
+	AllowedFlags []string
+
+	// PromotedFields lists top-level extra data keys that InitPlayerStateTable
+	// copies into dedicated, indexed columns generated from the extra data.
+	// GetPlayersByExtraDataField filters on a promoted key's column instead of
+	// the JSONB. A write whose value for the key can't be cast to the column
+	// type fails. Extra data is stored only as JSONB when none are promoted.
+	PromotedFields []PromotedField
+
+	// Validate, when set, is called by Save with the player's ExtraData before
+	// anything is written. A non-nil error aborts the save and is returned
+	// wrapped with ErrInvalidData.
+	Validate func(T) error
+
+	// AfterSave, when set, is called with a player's UUID after a write to
+	// that player succeeds, once any transaction has committed, e.g., to
+	// invalidate an external cache. Every write to existing players calls
+	// it, once for each player changed: the saves, flag and extra data
+	// helpers, phrase changes, transfers, merges, imports, soft deletes, and
+	// restores. Writes that change many players in one statement, namely
+	// DecayInactivePlayers, RecalculateAllLevels, RefreshRanks, and the
+	// purges, call it once with uuid.Nil instead, if they changed any, meaning
+	// any player may have changed. InitPlayer, InitPlayers, and CreatePlayer
+	// only create players and don't call it, and neither does SaveContext,
+	// since the caller's transaction may still roll back.
+	// result describes the change in XP and level; SaveExact,
+	// ReplaceExtraData, and imports report only the new values, and writes
+	// that leave XP and level alone, such as deletes, restores, flag changes,
+	// and the player removed by a merge, report the zero SaveResult. It runs
+	// synchronously before the write method returns.
+	AfterSave func(id uuid.UUID, result SaveResult)
+
+	db        *sql.DB
+	replica   *sql.DB
+	tableName string
+
+	// table is tableName quoted for use in SQL; tableErr reports why it
+	// couldn't be, and fails every operation.
+	table    string
+	tableErr error
+
+	// Result of validating LevelCurve, computed on first use
+	curveOnce sync.Once
+	curveErr  error
+
+	// Columns quoted for SQL, or why they couldn't be, computed on first use
+	columnsOnce sync.Once
+	columns     Columns
+	columnsErr  error
+
+	// Lifecycle state used by Close
+	mu       sync.RWMutex
+	closed   bool
+	inflight sync.WaitGroup
+	ctx      context.Context
+	cancel   context.CancelFunc
+}
+
+// NewStore returns a Store for the given table with the default configuration;
+// new players start at level 1 with 0 XP.
+// tableName may be schema-qualified, such as "game.player_state". Unquoted
+// parts follow Postgres' rules and are case-insensitive; wrap a part in
+// double quotes to keep its case. Every operation of a Store created with an
+// invalid name fails with ErrInvalidData.
+func NewStore[T any](db *sql.DB, tableName string) *Store[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &Store[T]{
+		StartingLevel:     1,
+		AutoLevel:         true,
+		Columns:           DefaultColumns(),
+		MaxUserNameLength: defaultMaxLength,
+		MaxPhraseLength:   defaultMaxLength,
+		db:                db,
+		tableName:         tableName,
+		ctx:               ctx,
+		cancel:            cancel,
+	}
+
+	// Stores used only for their audit table are created without a name
+	if tableName != "" {
+		var table TableName
+		table, s.tableErr = NewTableName(tableName)
+		s.table = table.Quoted()
+	}
+	return s
+}
+
+// NewStoreForTable is like NewStore but takes a table name already validated
+// with NewTableName, so the name can't fail at the first operation.
+// A zero TableName makes every operation fail with ErrInvalidData.
+func NewStoreForTable[T any](db *sql.DB, table TableName) *Store[T] {
+	s := NewStore[T](db, "")
+	s.tableName = table.name
+	s.table = table.quoted
+	if s.table == "" {
+		s.tableErr = fmt.Errorf("%w: table name is empty", ErrInvalidData)
+	}
+	return s
+}
+
+// NewStoreWithReplica returns a Store that sends writes to primary and serves
+// getters and leaderboard queries from the read-only replica.
+// Reads performed as part of a write, such as Save's level-up lookup, always
+// use the primary so replication lag cannot affect the result.
+func NewStoreWithReplica[T any](primary, replica *sql.DB, tableName string) *Store[T] {
+	s := NewStore[T](primary, tableName)
+	s.replica = replica
+	return s
+}
+
+// SetQueryTimeout sets the Store's QueryTimeout to d, bounding each attempt at
+// a Store operation; zero or negative disables the timeout. Like setting the
+// field, it must be called before the Store is first used.
+func (s *Store[T]) SetQueryTimeout(d time.Duration) {
+	s.QueryTimeout = d
+}
+
+// Close stops the Store from accepting new operations and waits for in-flight
+// operations to finish. If ctx expires first, in-flight queries are canceled
+// and ctx's error is returned.
+// The Store is unusable after Close; every method returns ErrStoreClosed.
+// The underlying database connections are owned by the caller and are left open.
+func (s *Store[T]) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.inflight.Wait()
+		close(drained)
+	}()
+
+	defer s.cancel()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// do runs a single Store operation, tracking it so Close can wait for it,
+// bounding and retrying it according to the Store's QueryTimeout and
+// RetryPolicy, and reporting it to the Store's Observer under the name op.
+// Errors caused by a missing table are wrapped with ErrTableNotInitialized,
+// and those of an operation whose context ended, with the context's error.
+// The context passed to fn is canceled if Close gives up waiting.
+func (s *Store[T]) do(op string, fn func(ctx context.Context) error) error {
+	return s.doContext(s.ctx, op, fn)
+}
+
+// doContext is like do but also cancels fn's context when ctx is done.
+func (s *Store[T]) doContext(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	s.mu.RLock()
+	if s.closed {
+		s.mu.RUnlock()
+		return ErrStoreClosed
+	}
+	if s.tableErr != nil {
+		s.mu.RUnlock()
+		return s.tableErr
+	}
+	if err := s.checkCurve(); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	if err := s.checkColumns(); err != nil {
+		s.mu.RUnlock()
+		return err
+	}
+	s.inflight.Add(1)
+	s.mu.RUnlock()
+	defer s.inflight.Done()
+
+	if ctx != s.ctx {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		defer context.AfterFunc(s.ctx, cancel)()
+	}
+
+	attempt := fn
+	if s.QueryTimeout > 0 {
+		attempt = func(ctx context.Context) error {
+			ctx, cancel := context.WithTimeout(ctx, s.QueryTimeout)
+			defer cancel()
+			return contextError(ctx, fn(ctx))
+		}
+	}
+
+	start := time.Now()
+	err := contextError(ctx, s.Retry.run(ctx, attempt))
+	if s.Observer != nil {
+		s.Observer.ObserveQuery(op, time.Since(start), err)
+	}
+
+	if hasSQLState(err, sqlStateUndefinedTable) {
+		return fmt.Errorf("%w: %w", ErrTableNotInitialized, err)
+	}
+	return err
+}
+
+// contextError makes err match ctx's error with errors.Is when ctx ended
+// before err was returned. Drivers often report a canceled query with an
+// error of their own, such as Postgres' query_canceled, rather than ctx.Err.
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil || errors.Is(err, ctx.Err()) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ctx.Err(), err)
+}
+
+// Querier is the subset of database/sql shared by *sql.DB, *sql.Conn, and
+// *sql.Tx. Functions accepting a Querier can run inside a caller's transaction.
+type Querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// beginTx starts a transaction on the primary at the Store's IsolationLevel.
+func (s *Store[T]) beginTx(ctx context.Context) (*sql.Tx, error) {
+	return s.db.BeginTx(ctx, &sql.TxOptions{Isolation: s.IsolationLevel})
+}
+
+// reader returns the connection used for read-only queries.
+func (s *Store[T]) reader() *sql.DB {
+	if s.replica != nil {
+		return s.replica
+	}
+	return s.db
+}
+
+// now returns the current time according to the Store's Clock.
+func (s *Store[T]) now() time.Time {
+	if s.Clock != nil {
+		return s.Clock()
+	}
+	return time.Now()
+}
+
+// actor converts a PlayerState's UpdatedBy for storage, where an empty
+// actor is NULL.
+func actor(updatedBy string) sql.NullString {
+	return sql.NullString{String: updatedBy, Valid: updatedBy != ""}
+}
+
+// defaultMaxLength is the size of the VARCHAR user name and phrase columns
+// InitPlayerStateTable creates.
+const defaultMaxLength = 255
+
+// checkLengths reports whether username and phrase fit the Store's limits.
+func (s *Store[T]) checkLengths(username, phrase string) error {
+	if n := utf8.RuneCountInString(username); s.MaxUserNameLength > 0 && n > s.MaxUserNameLength {
+		return fmt.Errorf("%w: username is %d characters, max is %d", ErrInvalidData, n, s.MaxUserNameLength)
+	}
+
+	if n := utf8.RuneCountInString(phrase); s.PhraseHasher == nil && s.MaxPhraseLength > 0 && n > s.MaxPhraseLength {
+		return fmt.Errorf("%w: phrase is %d characters, max is %d", ErrInvalidData, n, s.MaxPhraseLength)
+	}
+	return nil
+}
+
+// checkLimit reports whether a list limit, named what in the error, is
+// positive. Every method returning a list of players or leaders rejects a
+// zero or negative limit this way. There is no upper bound; callers should
+// page through large results instead of requesting them at once.
+func checkLimit(what string, limit int) error {
+	if limit <= 0 {
+		return fmt.Errorf("%w: %s must be greater than zero", ErrInvalidData, what)
+	}
+	return nil
+}
+
+// InitPlayerStateTable creates the player state table, using the Store's
+// column names, if it doesn't exist
+func (s *Store[T]) InitPlayerStateTable() error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	query := s.expand(`
+		CREATE TABLE IF NOT EXISTS {table} (
+			{id} UUID NOT NULL DEFAULT gen_random_uuid(),
+			{season_id} TEXT NOT NULL DEFAULT '',
+			{phrase} VARCHAR(255) NOT NULL,
+			{user_name} VARCHAR(255) NOT NULL,
+			{level} INT4 NOT NULL DEFAULT 1,
+			{xp} INT8 NOT NULL DEFAULT 0,
+			{last_updated} TIMESTAMPTZ NOT NULL DEFAULT now(),
+			{flags} JSONB DEFAULT '{}',
+			{extra_data} JSONB DEFAULT '{}',
+			{deleted_at} TIMESTAMPTZ,
+			{updated_by} TEXT,
+			{rank} INT8,
+			{last_decayed} TIMESTAMPTZ,
+			PRIMARY KEY ({id}, {season_id}),
+			UNIQUE ({phrase}, {season_id})
+		)
+	`)
+
+	// Bring tables created by earlier versions up to date
+	migration := s.expand(`
+		ALTER TABLE {table}
+		ADD COLUMN IF NOT EXISTS {deleted_at} TIMESTAMPTZ,
+		ADD COLUMN IF NOT EXISTS {updated_by} TEXT,
+		ADD COLUMN IF NOT EXISTS {rank} INT8,
+		ADD COLUMN IF NOT EXISTS {season_id} TEXT NOT NULL DEFAULT '',
+		ADD COLUMN IF NOT EXISTS {last_decayed} TIMESTAMPTZ
+	`)
+
+	// Secondary indexes, named after the table, and the queries they support
+	indexes := []struct{ suffix, columns string }{
+		{"level_xp_idx", "{level} DESC, {xp} DESC"}, // GetLeaderboardByLevel
+		{"last_updated_idx", "{last_updated}"},      // GetPlayersUpdatedSince
+	}
+
+	// Promoted fields get a generated column, filtered on by
+	// GetPlayersByExtraDataField
+	promotions := make([]string, len(s.PromotedFields))
+	for i, f := range s.PromotedFields {
+		definition, err := f.definition(s.quotedColumns().ExtraData)
+		if err != nil {
+			return err
+		}
+
+		column, err := f.column()
+		if err != nil {
+			return err
+		}
+
+		promotions[i] = s.expand(`
+		ALTER TABLE {table}
+		ADD COLUMN IF NOT EXISTS ` + definition)
+		indexes = append(indexes, struct{ suffix, columns string }{strings.ToLower(f.Column) + "_idx", column})
+	}
+
+	indexQueries := make([]string, len(indexes))
+	for i, index := range indexes {
+		name, err := indexName(s.tableName, index.suffix)
+		if err != nil {
+			return err
+		}
+
+		indexQueries[i] = `
+		CREATE INDEX IF NOT EXISTS ` + name + s.expand(`
+		ON {table} (`+index.columns+`)
+	`)
+	}
+
+	return s.do("init_table", func(ctx context.Context) error {
+		_, err := s.db.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to create player state table: %w", err)
+		}
+
+		_, err = s.db.ExecContext(ctx, migration)
+		if err != nil {
+			return fmt.Errorf("failed to migrate player state table: %w", err)
+		}
+
+		for _, promotion := range promotions {
+			_, err = s.db.ExecContext(ctx, promotion)
+			if err != nil {
+				return fmt.Errorf("failed to add promoted column: %w", err)
+			}
+		}
+
+		for _, indexQuery := range indexQueries {
+			_, err = s.db.ExecContext(ctx, indexQuery)
+			if err != nil {
+				return fmt.Errorf("failed to create player state index: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// InitPlayer creates a new player in the database.
+// ErrPlayerExists is returned if a player already has the id or the phrase;
+// for a phrase the error also matches ErrPhraseTaken.
+func (s *Store[T]) InitPlayer(id uuid.UUID, username, phrase string) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if username == "" || phrase == "" {
+		return fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths(username, phrase); err != nil {
+		return err
+	}
+
+	return s.do("init_player", func(ctx context.Context) error {
+		return s.initPlayer(ctx, s.db, id, username, phrase)
+	})
+}
+
+// initPlayer inserts a player row with the Store's starting level and XP.
+// A conflicting id or phrase is reported as ErrPlayerExists without failing
+// the statement, so a transaction q belongs to stays usable.
+func (s *Store[T]) initPlayer(ctx context.Context, q Querier, id uuid.UUID, username, phrase string) error {
+	query := s.expand(`
+		INSERT INTO {table} ({id}, {season_id}, {user_name}, {phrase}, {level}, {xp}, {extra_data})
+		VALUES ($1, {season_value}, $2, $3, $4, $5, $6)
+		ON CONFLICT DO NOTHING
+		`)
+
+	exists := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {season})
+		`)
+
+	stored, err := s.storedPhrase(phrase)
+	if err != nil {
+		return err
+	}
+
+	extraData, err := s.defaultExtraData()
+	if err != nil {
+		return err
+	}
+
+	result, err := q.ExecContext(ctx, query, s.seasonArgs(id, username, stored, s.StartingLevel, s.StartingXP, extraData)...)
+	if err != nil {
+		return fmt.Errorf("failed to create player: %w", err)
+	}
+
+	if requireRowAffected(result) == nil {
+		return nil
+	}
+
+	// Nothing was inserted; tell a taken id apart from a taken phrase
+	var found bool
+	if err := q.QueryRowContext(ctx, exists, s.seasonArgs(id)...).Scan(&found); err != nil {
+		return fmt.Errorf("failed to check player existence: %w", err)
+	}
+
+	if found {
+		return fmt.Errorf("%w: id %s", ErrPlayerExists, id)
+	}
+	return fmt.Errorf("%w: %w: %q", ErrPlayerExists, ErrPhraseTaken, phrase)
+}
+
+// CreatePlayer creates a new player with a fresh UUID and returns their full
+// state as stored, database defaults included, in a single round trip.
+// ErrPhraseTaken is returned if another player already holds the phrase.
+func (s *Store[T]) CreatePlayer(username, phrase string) (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if username == "" || phrase == "" {
+		return nil, fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths(username, phrase); err != nil {
+		return nil, err
+	}
+
+	stored, err := s.storedPhrase(phrase)
+	if err != nil {
+		return nil, err
+	}
+
+	extraData, err := s.defaultExtraData()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		INSERT INTO {table} ({id}, {season_id}, {user_name}, {phrase}, {level}, {xp}, {extra_data})
+		VALUES ($1, {season_value}, $2, $3, $4, $5, $6)
+		RETURNING {player_columns}
+		`)
+
+	id := uuid.New()
+	var state *PlayerState[T]
+	err = s.do("create_player", func(ctx context.Context) error {
+		row := s.db.QueryRowContext(ctx, query, s.seasonArgs(id, username, stored, s.StartingLevel, s.StartingXP, extraData)...)
+
+		var err error
+		state, err = s.scanPlayer(row)
+		return err
+	})
+	if hasSQLState(err, sqlStateUniqueViolation) {
+		return nil, fmt.Errorf("%w: %q", ErrPhraseTaken, phrase)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create player: %w", err)
+	}
+
+	return state, nil
+}
+
+// GetUserStateByID takes the UUID for a player and returns a player state struct.
+func (s *Store[T]) GetUserStateByID(id uuid.UUID) (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	var state *PlayerState[T]
+	err := s.do("get_by_id", func(ctx context.Context) error {
+		var err error
+		state, err = s.getUserStateByID(ctx, s.reader(), id)
+		return err
+	})
+	return state, err
+}
+
+// getUserStateByID loads a player by UUID using the given connection.
+func (s *Store[T]) getUserStateByID(ctx context.Context, q Querier, id uuid.UUID) (*PlayerState[T], error) {
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	state, err := s.scanPlayer(q.QueryRowContext(ctx, query, s.seasonArgs(id)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player data: %w", err)
+	}
+
+	return state, nil
+}
+
+// getUserStateForUpdate loads a player by ID and locks their row until the
+// end of the transaction q belongs to.
+func (s *Store[T]) getUserStateForUpdate(ctx context.Context, q Querier, id uuid.UUID) (*PlayerState[T], error) {
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		FOR UPDATE
+		`)
+
+	state, err := s.scanPlayer(q.QueryRowContext(ctx, query, s.seasonArgs(id)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock player: %w", err)
+	}
+
+	return state, nil
+}
+
+// GetUserStateByPhrase takes in the user passphrase and returns a PlayerState struct.
+func (s *Store[T]) GetUserStateByPhrase(phrase string) (*PlayerState[T], error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if phrase == "" {
+		return nil, fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	var state *PlayerState[T]
+	err := s.do("get_by_phrase", func(ctx context.Context) error {
+		var err error
+		state, err = s.getUserStateByPhrase(ctx, s.reader(), phrase)
+		return err
+	})
+	return state, err
+}
+
+// getUserStateByPhrase loads a player by phrase using the given connection.
+func (s *Store[T]) getUserStateByPhrase(ctx context.Context, q Querier, phrase string) (*PlayerState[T], error) {
+	match, err := s.phraseMatch()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE ` + match + ` AND {live}
+		`)
+
+	phrase, err = s.storedPhrase(phrase)
+	if err != nil {
+		return nil, err
+	}
+
+	state, err := s.scanPlayer(q.QueryRowContext(ctx, query, s.seasonArgs(phrase)...))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to query player data by phrase: %w", err)
+	}
+
+	return state, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanPlayer reads a row selected with {player_columns} into a PlayerState.
+// Scan errors, including sql.ErrNoRows, are returned unwrapped.
+func (s *Store[T]) scanPlayer(row rowScanner) (*PlayerState[T], error) {
+	var state PlayerState[T]
+	state.Flags = make(map[string]bool)
+
+	var rawID string
+	var flagsJSON, extraJSON []byte
+	var deletedAt sql.NullTime
+	var updatedBy sql.NullString
+	var rank sql.NullInt64
+	err := row.Scan(
+		&rawID,
+		&state.UserName,
+		&state.Phrase,
+		&state.Level,
+		&state.XP,
+		&state.LastUpdated,
+		&flagsJSON,
+		&extraJSON,
+		&deletedAt,
+		&updatedBy,
+		&rank,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	state.ID, err = parseID(rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	if deletedAt.Valid {
+		state.DeletedAt = &deletedAt.Time
+	}
+	state.UpdatedBy = updatedBy.String
+	state.Rank = uint64(rank.Int64)
+
+	if err := s.decodeJSONColumns(&state, flagsJSON, extraJSON); err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// parseID parses a player id scanned as a string. Parsing it ourselves means
+// a corrupted value in a text column produces a clear error rather than an
+// opaque driver conversion failure.
+func parseID(rawID string) (uuid.UUID, error) {
+	id, err := uuid.Parse(rawID)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("%w: malformed id %q: %v", ErrInvalidData, rawID, err)
+	}
+	return id, nil
+}
+
+// decodeJSONColumns unmarshals the flags and extra data columns into state.
+// Either column may be NULL, or not selected, leaving its field as is.
+func (s *Store[T]) decodeJSONColumns(state *PlayerState[T], flagsJSON, extraJSON []byte) error {
+	if len(flagsJSON) > 0 {
+		if err := s.codec().Unmarshal(flagsJSON, &state.Flags); err != nil {
+			return fmt.Errorf("failed to unmarshal flags: %w", err)
+		}
+	}
+
+	if len(extraJSON) > 0 {
+		if err := checkExtraDataType[T](); err != nil {
+			return err
+		}
+
+		// Rows written before nil extra data was normalized may hold null
+		if string(extraJSON) == "null" && isObjectType(reflect.TypeOf((*T)(nil)).Elem()) {
+			extraJSON = emptyObject
+		}
+
+		if err := s.decodeExtraData(extraJSON, &state.ExtraData); err != nil {
+			return fmt.Errorf("failed to unmarshal extra data: %w", err)
+		}
+	}
+	return nil
+}
+
+// PhraseExists reports whether a player with the given phrase is already stored.
+// It is cheaper than GetUserStateByPhrase since no row data is scanned or unmarshaled.
+// Soft-deleted players still hold their phrase and are counted.
+func (s *Store[T]) PhraseExists(phrase string) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if phrase == "" {
+		return false, fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	phrase, err := s.storedPhrase(phrase)
+	if err != nil {
+		return false, err
+	}
+
+	match, err := s.phraseMatch()
+	if err != nil {
+		return false, err
+	}
+
+	query := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE ` + match + ` AND {season})
+		`)
+
+	var exists bool
+	err = s.do("phrase_exists", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(phrase)...).Scan(&exists)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check phrase existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// PlayerExists reports whether a player with the given UUID is already stored,
+// including soft-deleted players.
+func (s *Store[T]) PlayerExists(id uuid.UUID) (bool, error) {
+	if s.db == nil {
+		return false, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return false, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		SELECT EXISTS(SELECT 1 FROM {table} WHERE {id} = $1 AND {season})
+		`)
+
+	var exists bool
+	err := s.do("player_exists", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(id)...).Scan(&exists)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to check player existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// GetXPAndLevel returns a player's XP and level without reading their flags
+// or extra data, for hot paths that need nothing else.
+// ErrPlayerNotFound is returned if no player has the given UUID.
+func (s *Store[T]) GetXPAndLevel(id uuid.UUID) (xp uint64, level uint32, err error) {
+	if s.db == nil {
+		return 0, 0, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return 0, 0, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	query := s.expand(`
+		SELECT {xp}, {level}
+		FROM {table}
+		WHERE {id} = $1 AND {live}
+		`)
+
+	err = s.do("get_xp_and_level", func(ctx context.Context) error {
+		return s.reader().QueryRowContext(ctx, query, s.seasonArgs(id)...).Scan(&xp, &level)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, 0, ErrPlayerNotFound
+	}
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to query xp and level: %w", err)
+	}
+
+	return xp, level, nil
+}
+
+// Save takes the existing player and updates the DB with the new player information.
+// If the player does not exist; this function will initiate a DB entry with the provided
+// data, starting from the Store's StartingLevel and StartingXP, and return.
+// The returned SaveResult describes the XP and level before and after the save.
+// On success p's LastUpdated, Level, and XP hold the values the database
+// stored, read back from the update itself.
+// ErrAwardTooSoon is returned, and p left unchanged, if the award falls within
+// the Store's MinAwardInterval.
+func (s *Store[T]) Save(p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	return s.SaveWithOptions(p, AwardXP(xpIncrease))
+}
+
+// saveOptions adjusts how saveWith writes a player.
+type saveOptions struct {
+	// keepLastUpdated leaves an existing player's last update time as is.
+	keepLastUpdated bool
+
+	// xpDecrease is XP to take away instead of awarding any.
+	xpDecrease uint64
+
+	// xpIncrease is the XP awarded by SaveWithOptions.
+	xpIncrease uint64
+
+	// dryRun previews the save instead of writing it.
+	dryRun bool
+
+	// expectVersion, when non-nil, is the last update time the stored
+	// player must still have for the save to be written.
+	expectVersion *time.Time
+
+	// level, when non-nil, is written as the player's level instead of the
+	// one derived from their XP.
+	level *uint32
+
+	// clock, when set, replaces the Store's Clock for this save.
+	clock func() time.Time
+}
+
+// saveWith validates p and saves it according to opts, on tx if it is
+// non-nil and otherwise on the Store's primary.
+func (s *Store[T]) saveWith(ctx context.Context, tx Querier, p *PlayerState[T], xpIncrease uint64, opts saveOptions) (SaveResult, error) {
+	if s.db == nil && tx == nil {
+		return SaveResult{}, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if p == nil {
+		return SaveResult{}, fmt.Errorf("%w: player cannot be nil", ErrInvalidData)
+	}
+
+	if p.ID == uuid.Nil {
+		// Generate a new ID if needed
+		p.ID = uuid.New()
+	}
+
+	if p.UserName == "" || p.Phrase == "" {
+		return SaveResult{}, fmt.Errorf("%w: username and phrase cannot be empty", ErrInvalidData)
+	}
+
+	if err := s.checkLengths(p.UserName, p.Phrase); err != nil {
+		return SaveResult{}, err
+	}
+
+	if err := s.checkFlags(p.Flags); err != nil {
+		return SaveResult{}, err
+	}
+
+	if s.Validate != nil {
+		if err := s.Validate(p.ExtraData); err != nil {
+			return SaveResult{}, fmt.Errorf("%w: extra data: %w", ErrInvalidData, err)
+		}
+	}
+
+	var result SaveResult
+	err := s.doContext(ctx, "save", func(ctx context.Context) error {
+		if tx != nil {
+			var err error
+			result, err = s.save(ctx, tx, p, xpIncrease, opts)
+			return err
+		}
+
+		if !s.LockOnSave {
+			var err error
+			result, err = s.save(ctx, nil, p, xpIncrease, opts)
+			return err
+		}
+
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		result, err = s.save(ctx, tx, p, xpIncrease, opts)
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit save: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	// A caller's transaction may still roll back
+	if tx == nil {
+		s.afterSave(p.ID, result)
+	}
+	return result, nil
+}
+
+// afterSave calls the Store's AfterSave hook, if any.
+func (s *Store[T]) afterSave(id uuid.UUID, result SaveResult) {
+	if s.AfterSave != nil {
+		s.AfterSave(id, result)
+	}
+}
+
+// afterBulkSave calls the Store's AfterSave hook once with uuid.Nil after a
+// write that changed n players at once, if it changed any.
+func (s *Store[T]) afterBulkSave(n int64) {
+	if n > 0 {
+		s.afterSave(uuid.Nil, SaveResult{})
+	}
+}
+
+// save performs Save once its input has been validated. When tx is non-nil
+// the player's row is locked and every statement runs in tx.
+func (s *Store[T]) save(ctx context.Context, tx Querier, p *PlayerState[T], xpIncrease uint64, opts saveOptions) (SaveResult, error) {
+	// Read from the primary so replication lag can't skew the level-up math.
+	var q Querier = s.db
+	getPlayer := s.getUserStateByID
+	if tx != nil {
+		q = tx
+		getPlayer = s.getUserStateForUpdate
+	}
+
+	player, err := getPlayer(ctx, q, p.ID)
+	if err != nil && !errors.Is(err, ErrPlayerNotFound) {
+		return SaveResult{}, fmt.Errorf("failed to fetch player state: %w", err)
+	}
+
+	if opts.expectVersion != nil {
+		if player == nil {
+			return SaveResult{}, ErrPlayerNotFound
+		}
+
+		if !player.LastUpdated.Equal(*opts.expectVersion) {
+			return SaveResult{}, ErrVersionMismatch
+		}
+	}
+
+	result, err := s.nextState(player, p, xpIncrease)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	if opts.xpDecrease > 0 {
+		result = s.decreased(result, opts.xpDecrease)
+	}
+
+	if opts.level != nil {
+		result.Level = *opts.level
+	}
+
+	now := s.now
+	if opts.clock != nil {
+		now = opts.clock
+	}
+	xpDelta := int64(result.XP) - int64(result.PreviousXP)
+
+	if result.Created {
+		log.Printf("Creating new player: %s\n", p.UserName)
+
+		// Initialize any nil fields
+		if p.Flags == nil {
+			p.Flags = make(map[string]bool)
+		}
+
+		if s.DefaultExtraData != nil && reflect.ValueOf(&p.ExtraData).Elem().IsZero() {
+			p.ExtraData = s.DefaultExtraData()
+		}
+
+		// Set default values for new player
+		p.Level = result.Level
+		p.XP = result.XP
+		p.LastUpdated = now()
+
+		// Create new player
+		err = s.initPlayer(ctx, q, p.ID, p.UserName, p.Phrase)
+		if err != nil {
+			return SaveResult{}, fmt.Errorf("failed to initialize player: %w", err)
+		}
+
+		// If we just initialized with base values, we need to update with the complete state
+		extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+		if err != nil {
+			return SaveResult{}, err
+		}
+
+		flags, err := marshalFlags(s.codec(), p.Flags)
+		if err != nil {
+			return SaveResult{}, err
+		}
+
+		query := s.expand(`
+		UPDATE {table}
+		SET {level} = $1,
+			{xp} = $2,
+			{extra_data} = $3,
+			{flags} = $4,
+			{last_updated} = $5,
+			{updated_by} = $7
+		WHERE {id} = $6 AND {season}
+		RETURNING {last_updated}, {level}, {xp}
+			`)
+
+		err = s.execWithAudit(ctx, tx, p.ID, xpDelta, p.XP, p.Level, storedState(p), query, s.seasonArgs(
+			p.Level,
+			p.XP,
+			extraData,
+			flags,
+			p.LastUpdated,
+			p.ID,
+			actor(p.UpdatedBy),
+		)...)
+
+		if err != nil {
+			return SaveResult{}, fmt.Errorf("failed to update new player data: %w", err)
+		}
+
+		return result, nil
+	}
+
+	// Update existing player
+	previous := *p
+	updated := now()
+	p.XP = result.XP
+	p.Level = result.Level
+	p.LastUpdated = updated
+
+	// A NULL last update keeps the stored one, along with who made it
+	lastUpdated := sql.NullTime{Time: updated, Valid: true}
+	if opts.keepLastUpdated {
+		lastUpdated.Valid = false
+		p.LastUpdated = player.LastUpdated
+		p.UpdatedBy = player.UpdatedBy
+	}
+
+	extraData, err := marshalExtraData(s.codec(), p.ExtraData)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	flags, err := marshalFlags(s.codec(), p.Flags)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	// The guards below are added before expanding, so the season is bound
+	// after their arguments
+	query := `
+	UPDATE {table}
+	SET {level} = $1,
+		{xp} = $2,
+		{extra_data} = $3,
+		{flags} = $4,
+		{last_updated} = COALESCE($5::timestamptz, {last_updated}),
+		{updated_by} = CASE WHEN $5::timestamptz IS NULL THEN {updated_by} ELSE $7 END
+	WHERE {id} = $6 AND {season}
+		`
+
+	args := []any{
+		p.Level,
+		p.XP,
+		extraData,
+		flags,
+		lastUpdated,
+		p.ID,
+		actor(p.UpdatedBy),
+	}
+
+	rateLimited := s.MinAwardInterval > 0 && xpIncrease > 0
+	if rateLimited {
+		query += ` AND {last_updated} <= $8`
+		args = append(args, updated.Add(-s.MinAwardInterval))
+	}
+
+	// Guard against a write landing between the read and the update
+	if opts.expectVersion != nil {
+		query += ` AND {last_updated} = $` + strconv.Itoa(len(args)+1)
+		args = append(args, *opts.expectVersion)
+	}
+	query = s.expand(query + ` RETURNING {last_updated}, {level}, {xp}`)
+
+	err = s.execWithAudit(ctx, tx, p.ID, xpDelta, p.XP, p.Level, storedState(p), query, s.seasonArgs(args...)...)
+	guarded := rateLimited || opts.expectVersion != nil
+	if guarded && errors.Is(err, ErrPlayerNotFound) {
+		p.XP, p.Level, p.LastUpdated, p.UpdatedBy = previous.XP, previous.Level, previous.LastUpdated, previous.UpdatedBy
+
+		// Without an expected version, only the award interval guards the
+		// update, even when a concurrent award landed after the read
+		if opts.expectVersion == nil {
+			return SaveResult{}, ErrAwardTooSoon
+		}
+
+		// The version matched when read, so only a recent award means too soon
+		if rateLimited && player.LastUpdated.After(updated.Add(-s.MinAwardInterval)) {
+			return SaveResult{}, ErrAwardTooSoon
+		}
+		return SaveResult{}, ErrVersionMismatch
+	}
+
+	if err != nil {
+		return SaveResult{}, fmt.Errorf("failed to update player data: %w", err)
+	}
+
+	return result, nil
+}
+
+// storedState returns the destinations that a Save's RETURNING clause scans
+// into, so p ends up holding exactly the last update time, level, and XP
+// the database stored, e.g., with the timestamp at its stored precision.
+func storedState[T any](p *PlayerState[T]) []any {
+	return []any{&p.LastUpdated, &p.Level, &p.XP}
+}
+
+// nextState computes the XP and level a Save of p awarding xpIncrease results
+// in. stored is the player's current row, or nil if the player doesn't exist yet.
+// ErrInvalidData is returned if the award would take the player's XP past
+// what the xp column holds.
+func (s *Store[T]) nextState(stored, p *PlayerState[T], xpIncrease uint64) (SaveResult, error) {
+	var result SaveResult
+	if stored == nil {
+		result = SaveResult{
+			PreviousXP:    s.StartingXP,
+			PreviousLevel: s.StartingLevel,
+			Level:         s.StartingLevel,
+			Created:       true,
+		}
+	} else {
+		result = SaveResult{
+			PreviousXP:    stored.XP,
+			PreviousLevel: stored.Level,
+			Level:         p.Level,
+		}
+	}
+
+	var err error
+	result.XP, err = addXP(result.PreviousXP, xpIncrease)
+	if err != nil {
+		return SaveResult{}, err
+	}
+
+	return s.awarded(result), nil
+}
+
+// addXP returns xp plus award, or ErrInvalidData if the total wouldn't fit in
+// the INT8 xp column.
+func addXP(xp, award uint64) (uint64, error) {
+	if award > math.MaxInt64 || xp > math.MaxInt64-award {
+		return 0, fmt.Errorf("%w: xp total is too large", ErrInvalidData)
+	}
+	return xp + award, nil
+}
+
+// awarded applies the Store's GateLevel and AutoLevel to result, whose XP
+// already includes an award.
+func (s *Store[T]) awarded(result SaveResult) SaveResult {
+	if s.GateLevel > 0 {
+		result = s.gated(result)
+	}
+
+	// Derive the level from total XP; a large award may span several levels
+	if s.AutoLevel {
+		result.Level = max(result.Level, s.curve().levelForXP(result.XP))
+	}
+
+	if s.GateLevel > 0 {
+		result.Level = min(result.Level, max(s.GateLevel, result.PreviousLevel))
+	}
+
+	return result
+}
+
+// gated caps result's XP at the Store's GateLevel, recording the XP discarded.
+func (s *Store[T]) gated(result SaveResult) SaveResult {
+	limit := max(s.gateXP(), result.PreviousXP)
+	if result.XP > limit {
+		result.DiscardedXP = result.XP - limit
+		result.XP = limit
+	}
+	return result
+}
+
+// gateXP returns the most XP a player can hold at the Store's GateLevel.
+func (s *Store[T]) gateXP() uint64 {
+	if s.GateLevel == math.MaxUint32 {
+		return math.MaxUint64
+	}
+	return max(s.curve()(s.GateLevel+1), 1) - 1
+}
+
+// decreased takes xpDecrease XP away from result, clamping at zero. When
+// AutoLevel is enabled the level drops to match the remaining XP, never below
+// the Store's StartingLevel, as TransferXP demotes a sender.
+func (s *Store[T]) decreased(result SaveResult, xpDecrease uint64) SaveResult {
+	result.XP -= min(result.XP, xpDecrease)
+	if s.AutoLevel {
+		result.Level = min(result.Level, max(s.StartingLevel, s.curve().levelForXP(result.XP)))
+	}
+	return result
+}
+
+// GetLeaderboard fetches the top users by XP, ordering players with equal XP
+// by the Store's Tiebreaker.
+func (s *Store[T]) GetLeaderboard(limit int) ([]Leader, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {user_name}, {level}, {xp}
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $1`)
+
+	var users []Leader
+	err = s.do("leaderboard", func(ctx context.Context) error {
+		var err error
+		users, err = queryLeaders(ctx, s.reader(), query, s.seasonArgs(limit)...)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// QueryLeaderboardRows runs the leaderboard query and returns its rows for
+// custom scanning, as an escape hatch for cases GetLeaderboard doesn't cover.
+// Rows hold every player column in scanPlayer's order: id, user name,
+// phrase, level, XP, last update, flags, extra data, deletion time, updater,
+// and stored rank, in GetLeaderboard's order. The caller must Close the rows.
+//
+// The query runs on ctx alone; the Store's QueryTimeout, Retry, and Observer
+// don't apply, and Close doesn't wait for the rows to be closed.
+func (s *Store[T]) QueryLeaderboardRows(ctx context.Context, limit int) (*sql.Rows, error) {
+	if s.db == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if err := checkLimit("leaderboard limit", limit); err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	if closed {
+		return nil, ErrStoreClosed
+	}
+
+	if s.tableErr != nil {
+		return nil, s.tableErr
+	}
+
+	if err := s.checkColumns(); err != nil {
+		return nil, err
+	}
+
+	tiebreak, err := s.Tiebreaker.orderBy()
+	if err != nil {
+		return nil, err
+	}
+
+	query := s.expand(`
+		SELECT {player_columns}
+		FROM {table}
+		WHERE {live}
+		ORDER BY {xp} DESC, ` + tiebreak + `
+		LIMIT $1`)
+
+	rows, err := s.reader().QueryContext(ctx, query, s.seasonArgs(limit)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+
+	return rows, nil
+}
+
+// queryLeaders runs a query selecting user name, level, and XP and collects
+// the rows into Leader entries.
+func queryLeaders(ctx context.Context, q Querier, query string, args ...any) ([]Leader, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query leaderboard: %w", err)
+	}
+	defer rows.Close()
+
+	var users []Leader
+	for rows.Next() {
+		var user Leader
+		err := rows.Scan(
+			&user.UserName,
+			&user.Level,
+			&user.XP,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan leaderboard row: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating through leaderboard rows: %w", err)
+	}
+
+	return users, nil
+}
@@ -0,0 +1,344 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCheckLengths(t *testing.T) {
+	s := NewStore[any](nil, "players")
+
+	tests := []struct {
+		name     string
+		username string
+		phrase   string
+		valid    bool
+	}{
+		{"at limit", strings.Repeat("a", 255), strings.Repeat("b", 255), true},
+		{"username over limit", strings.Repeat("a", 256), "phrase", false},
+		{"phrase over limit", "player", strings.Repeat("b", 256), false},
+		{"multibyte at limit", strings.Repeat("é", 255), strings.Repeat("日", 255), true},
+		{"multibyte over limit", strings.Repeat("é", 256), "phrase", false},
+	}
+
+	for _, tt := range tests {
+		err := s.checkLengths(tt.username, tt.phrase)
+		if tt.valid && err != nil {
+			t.Errorf("%s: checkLengths() = %v, want nil", tt.name, err)
+		}
+
+		if !tt.valid && !errors.Is(err, ErrInvalidData) {
+			t.Errorf("%s: checkLengths() = %v, want ErrInvalidData", tt.name, err)
+		}
+	}
+}
+
+func TestCheckLengthsConfigured(t *testing.T) {
+	s := NewStore[any](nil, "players")
+	s.MaxUserNameLength = 0
+	s.MaxPhraseLength = 8
+
+	if err := s.checkLengths(strings.Repeat("a", 1000), "12345678"); err != nil {
+		t.Errorf("checkLengths() = %v, want nil", err)
+	}
+
+	if err := s.checkLengths("player", "123456789"); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("checkLengths() = %v, want ErrInvalidData", err)
+	}
+
+	// The stored hash has its own length
+	s.PhraseHasher = SHA256PhraseHasher{}
+	if err := s.checkLengths("player", "123456789"); err != nil {
+		t.Errorf("checkLengths() with a hasher = %v, want nil", err)
+	}
+}
+
+func TestInitPlayerRejectsLongUserName(t *testing.T) {
+	m := NewMemoryStore[any](nil)
+
+	if err := m.InitPlayer(uuid.New(), strings.Repeat("a", 255), "phrase-1"); err != nil {
+		t.Fatalf("InitPlayer() at the limit = %v, want nil", err)
+	}
+
+	if err := m.InitPlayer(uuid.New(), strings.Repeat("a", 256), "phrase-2"); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("InitPlayer() over the limit = %v, want ErrInvalidData", err)
+	}
+}
+
+func TestListLimits(t *testing.T) {
+	s := NewStore[any](fakeDB(t, nil), "players")
+	s.AuditTable = "player_xp_history"
+	m := NewMemoryStore[any](nil)
+
+	calls := map[string]func(limit int) error{
+		"GetLeaderboard": func(limit int) error {
+			_, err := s.GetLeaderboard(limit)
+			return err
+		},
+		"QueryLeaderboardRows": func(limit int) error {
+			_, err := s.QueryLeaderboardRows(context.Background(), limit)
+			return err
+		},
+		"GetLeaderboardByLevel": func(limit int) error {
+			_, err := s.GetLeaderboardByLevel(limit)
+			return err
+		},
+		"GetLeaderboardByLevelRange": func(limit int) error {
+			_, err := s.GetLeaderboardByLevelRange(1, 10, limit)
+			return err
+		},
+		"GetLeaderboardWithRank": func(limit int) error {
+			_, err := s.GetLeaderboardWithRank(limit, 0)
+			return err
+		},
+		"GetLeaderboardByFlags": func(limit int) error {
+			_, err := s.GetLeaderboardByFlags(FlagFilter{"premium": true}, limit)
+			return err
+		},
+		"GetMultiTableLeaderboard": func(limit int) error {
+			_, err := GetMultiTableLeaderboard(s.db, []string{"players"}, limit)
+			return err
+		},
+		"CachedLeaderboard": func(limit int) error {
+			_, err := s.CachedLeaderboard(limit, time.Minute)
+			return err
+		},
+		"GetPlayersByFlag": func(limit int) error {
+			_, err := s.GetPlayersByFlag("premium", true, limit)
+			return err
+		},
+		"GetPlayersByXPRange": func(limit int) error {
+			_, err := s.GetPlayersByXPRange(0, 100, limit)
+			return err
+		},
+		"GetPlayersByExtraDataField": func(limit int) error {
+			_, err := s.GetPlayersByExtraDataField("class", "mage", limit)
+			return err
+		},
+		"GetPlayersUpdatedSince": func(limit int) error {
+			_, err := s.GetPlayersUpdatedSince(time.Time{}, limit)
+			return err
+		},
+		"IteratePlayers": func(limit int) error {
+			_, _, err := s.IteratePlayers(context.Background(), uuid.Nil, limit)
+			return err
+		},
+		"GetXPHistory": func(limit int) error {
+			_, err := s.GetXPHistory(uuid.New(), limit)
+			return err
+		},
+		"MemoryStore.GetLeaderboard": func(limit int) error {
+			_, err := m.GetLeaderboard(limit)
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		for _, limit := range []int{0, -1} {
+			if err := call(limit); !errors.Is(err, ErrInvalidData) {
+				t.Errorf("%s(%d) = %v, want ErrInvalidData", name, limit, err)
+			}
+		}
+	}
+}
+
+// noRows answers every query with an empty result.
+func noRows(string, []driver.NamedValue) ([][]driver.Value, error) {
+	return nil, nil
+}
+
+func TestErrorKinds(t *testing.T) {
+	nilDB := NewStore[any](nil, "players")
+	empty := NewStore[any](fakeDB(t, noRows), "players")
+	badTable := NewStore[any](fakeDB(t, nil), "bad name")
+	id := uuid.New()
+
+	tests := []struct {
+		name string
+		call func() error
+		want error
+	}{
+		{"GetUserStateByID nil db", func() error {
+			_, err := nilDB.GetUserStateByID(id)
+			return err
+		}, ErrDatabaseConnection},
+		{"Save nil db", func() error {
+			_, err := nilDB.Save(NewPlayerState[any]("player", "phrase"), 10)
+			return err
+		}, ErrDatabaseConnection},
+		{"GetLeaderboard nil db", func() error {
+			_, err := nilDB.GetLeaderboard(10)
+			return err
+		}, ErrDatabaseConnection},
+		{"GetUserStateByID nil id", func() error {
+			_, err := empty.GetUserStateByID(uuid.Nil)
+			return err
+		}, ErrInvalidData},
+		{"GetUserStateByPhrase empty phrase", func() error {
+			_, err := empty.GetUserStateByPhrase("")
+			return err
+		}, ErrInvalidData},
+		{"InitPlayer empty username", func() error {
+			return empty.InitPlayer(id, "", "phrase")
+		}, ErrInvalidData},
+		{"Save nil player", func() error {
+			_, err := empty.Save(nil, 10)
+			return err
+		}, ErrInvalidData},
+		{"TransferXP to self", func() error {
+			return empty.TransferXP(id, id, 10)
+		}, ErrInvalidData},
+		{"invalid table name", func() error {
+			_, err := badTable.GetUserStateByID(id)
+			return err
+		}, ErrInvalidData},
+		{"GetUserStateByID missing", func() error {
+			_, err := empty.GetUserStateByID(id)
+			return err
+		}, ErrPlayerNotFound},
+		{"GetXPAndLevel missing", func() error {
+			_, _, err := empty.GetXPAndLevel(id)
+			return err
+		}, ErrPlayerNotFound},
+		{"GetFlags missing", func() error {
+			_, err := empty.GetFlags(id)
+			return err
+		}, ErrPlayerNotFound},
+		{"ClearAllFlags missing", func() error {
+			return empty.ClearAllFlags(id)
+		}, ErrPlayerNotFound},
+		{"SoftDeletePlayer missing", func() error {
+			return empty.SoftDeletePlayer(id)
+		}, ErrPlayerNotFound},
+		{"MemoryStore.GetUserStateByID missing", func() error {
+			_, err := NewMemoryStore[any](nil).GetUserStateByID(id)
+			return err
+		}, ErrPlayerNotFound},
+	}
+
+	kinds := []error{ErrDatabaseConnection, ErrInvalidData, ErrPlayerNotFound}
+	for _, tt := range tests {
+		err := tt.call()
+		for _, kind := range kinds {
+			if got, want := errors.Is(err, kind), kind == tt.want; got != want {
+				t.Errorf("%s: errors.Is(%v, %v) = %t, want %t", tt.name, err, kind, got, want)
+			}
+		}
+	}
+}
+
+// fakeRow is a rowScanner returning fixed column values.
+type fakeRow []any
+
+func (r fakeRow) Scan(dest ...any) error {
+	if len(dest) != len(r) {
+		return fmt.Errorf("scanning %d columns into %d destinations", len(r), len(dest))
+	}
+
+	for i, value := range r {
+		if err := convertAssign(dest[i], value); err != nil {
+			return fmt.Errorf("column %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// convertAssign stores value in dest the way database/sql would for the
+// column types scanPlayer reads.
+func convertAssign(dest, value any) error {
+	if scanner, ok := dest.(sql.Scanner); ok {
+		return scanner.Scan(value)
+	}
+
+	target := reflect.ValueOf(dest).Elem()
+	v := reflect.ValueOf(value)
+	if !v.Type().ConvertibleTo(target.Type()) {
+		return fmt.Errorf("cannot store %T in %T", value, dest)
+	}
+	target.Set(v.Convert(target.Type()))
+	return nil
+}
+
+// playerRow returns the columns of a stored player in {player_columns} order.
+func playerRow(id string) fakeRow {
+	return fakeRow{
+		id,
+		"player",
+		"phrase",
+		int64(3),
+		int64(450),
+		time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		[]byte(`{"premium":true}`),
+		[]byte(`{}`),
+		nil,
+		nil,
+		nil,
+	}
+}
+
+func TestScanPlayer(t *testing.T) {
+	s := NewStore[any](nil, "players")
+	id := uuid.New()
+
+	p, err := s.scanPlayer(playerRow(id.String()))
+	if err != nil {
+		t.Fatalf("scanPlayer() = %v", err)
+	}
+
+	if p.ID != id || p.Level != 3 || p.XP != 450 || !p.Flags["premium"] {
+		t.Errorf("scanPlayer() = %+v", p)
+	}
+}
+
+func TestScanPlayerMalformedID(t *testing.T) {
+	s := NewStore[any](nil, "players")
+
+	for _, rawID := range []string{"", "not-a-uuid", "1234"} {
+		_, err := s.scanPlayer(playerRow(rawID))
+		if !errors.Is(err, ErrInvalidData) {
+			t.Errorf("scanPlayer(%q) = %v, want ErrInvalidData", rawID, err)
+		}
+	}
+}
+
+func TestInitPlayerConflicts(t *testing.T) {
+	tests := []struct {
+		name    string
+		idTaken bool
+		phrase  bool
+	}{
+		{"duplicate id", true, false},
+		{"duplicate phrase", false, true},
+	}
+
+	for _, tt := range tests {
+		db := fakeDB(t, func(query string, _ []driver.NamedValue) ([][]driver.Value, error) {
+			switch {
+			case strings.Contains(query, "INSERT"):
+				// ON CONFLICT DO NOTHING inserts nothing
+				return nil, nil
+			case strings.Contains(query, "EXISTS"):
+				return [][]driver.Value{{tt.idTaken}}, nil
+			}
+			return nil, fmt.Errorf("unexpected query: %s", query)
+		})
+
+		err := NewStore[any](db, "players").InitPlayer(uuid.New(), "player", "phrase")
+		if !errors.Is(err, ErrPlayerExists) {
+			t.Errorf("%s: InitPlayer() = %v, want ErrPlayerExists", tt.name, err)
+		}
+
+		if got := errors.Is(err, ErrPhraseTaken); got != tt.phrase {
+			t.Errorf("%s: errors.Is(%v, ErrPhraseTaken) = %t, want %t", tt.name, err, got, tt.phrase)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package ghostplay
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// TransferXP moves amount XP from one player to another in a single
+// transaction and recomputes both players' levels.
+func TransferXP(db *sql.DB, dbTableName string, from, to uuid.UUID, amount uint64) error {
+	return NewStore[any](db, dbTableName).TransferXP(from, to, amount)
+}
+
+// TransferXP moves amount XP from one player to another in a single
+// transaction. Both rows are locked for the duration so concurrent transfers
+// can't overdraw the sender.
+//
+// When AutoLevel is enabled the sender is demoted to match their remaining XP,
+// never below the Store's StartingLevel, and the recipient is promoted to
+// match their new total. The recipient is held to the Store's GateLevel as
+// Save would hold them: XP beyond the gate is discarded, though the sender
+// still gives up the whole amount. Both players' UpdatedBy is cleared.
+// ErrPlayerNotFound is returned if either player is missing,
+// ErrInsufficientXP if the sender holds less than amount, and ErrInvalidData
+// if the recipient's total wouldn't fit in the xp column.
+func (s *Store[T]) TransferXP(from, to uuid.UUID, amount uint64) error {
+	if s.db == nil {
+		return fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if from == uuid.Nil || to == uuid.Nil {
+		return fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	if from == to {
+		return fmt.Errorf("%w: cannot transfer xp to the same player", ErrInvalidData)
+	}
+
+	if amount == 0 {
+		return fmt.Errorf("%w: transfer amount must be greater than zero", ErrInvalidData)
+	}
+
+	var sent, received SaveResult
+	err := s.do("transfer_xp", func(ctx context.Context) error {
+		tx, err := s.beginTx(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		// Lock in id order so opposing transfers can't deadlock
+		query := s.expand(`
+			SELECT {id}, {xp}, {level}
+			FROM {table}
+			WHERE {id} = ANY($1::uuid[]) AND {live}
+			ORDER BY {id}
+			FOR UPDATE`)
+
+		rows, err := tx.QueryContext(ctx, query, s.seasonArgs(uuidArray([]uuid.UUID{from, to}))...)
+		if err != nil {
+			return fmt.Errorf("failed to lock players: %w", err)
+		}
+
+		type balance struct {
+			xp    uint64
+			level uint32
+		}
+		balances := make(map[uuid.UUID]balance, 2)
+		for rows.Next() {
+			var id uuid.UUID
+			var b balance
+			if err := rows.Scan(&id, &b.xp, &b.level); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan player row: %w", err)
+			}
+			balances[id] = b
+		}
+		rows.Close()
+
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating through player rows: %w", err)
+		}
+
+		sender, ok := balances[from]
+		if !ok {
+			return fmt.Errorf("%w: sender %s", ErrPlayerNotFound, from)
+		}
+
+		recipient, ok := balances[to]
+		if !ok {
+			return fmt.Errorf("%w: recipient %s", ErrPlayerNotFound, to)
+		}
+
+		if sender.xp < amount {
+			return fmt.Errorf("%w: sender has %d xp, needs %d", ErrInsufficientXP, sender.xp, amount)
+		}
+
+		total, err := addXP(recipient.xp, amount)
+		if err != nil {
+			return err
+		}
+
+		// The recipient is leveled as Save would level them, gate included
+		sent = SaveResult{PreviousXP: sender.xp, PreviousLevel: sender.level}
+		received = s.awarded(SaveResult{
+			PreviousXP:    recipient.xp,
+			PreviousLevel: recipient.level,
+			XP:            total,
+			Level:         recipient.level,
+		})
+
+		sender.xp -= amount
+		if s.AutoLevel {
+			sender.level = min(sender.level, max(s.StartingLevel, s.curve().levelForXP(sender.xp)))
+		}
+		recipient.xp, recipient.level = received.XP, received.Level
+
+		update := s.expand(`
+			UPDATE {table}
+			SET {xp} = $1,
+				{level} = $2,
+				{last_updated} = $3,
+				{updated_by} = NULL
+			WHERE {id} = $4 AND {season}`)
+
+		now := s.now()
+		if _, err := tx.ExecContext(ctx, update, s.seasonArgs(sender.xp, sender.level, now, from)...); err != nil {
+			return fmt.Errorf("failed to update sender: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, update, s.seasonArgs(recipient.xp, recipient.level, now, to)...); err != nil {
+			return fmt.Errorf("failed to update recipient: %w", err)
+		}
+
+		if err := s.recordXPChange(ctx, tx, from, -int64(amount), sender.xp); err != nil {
+			return err
+		}
+
+		if err := s.recordXPChange(ctx, tx, to, int64(received.XP-received.PreviousXP), recipient.xp); err != nil {
+			return err
+		}
+
+		if err := s.notifyChange(ctx, tx, PlayerChange{ID: from, XP: sender.xp, Level: sender.level}); err != nil {
+			return err
+		}
+
+		if err := s.notifyChange(ctx, tx, PlayerChange{ID: to, XP: recipient.xp, Level: recipient.level}); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transfer: %w", err)
+		}
+
+		sent.XP, sent.Level = sender.xp, sender.level
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.afterSave(from, sent)
+	s.afterSave(to, received)
+	return nil
+}
@@ -0,0 +1,49 @@
+package ghostplay
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// newPlayer creates a player on s holding xp.
+func newPlayer(t *testing.T, s *Store[any], xp uint64) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase "+id.String()); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+	awardXP(t, s, id, xp)
+	return id
+}
+
+func TestTransferXPGateLevel(t *testing.T) {
+	s := testStore[any](t)
+	from := newPlayer(t, s, 1_000)
+	to := newPlayer(t, s, 0)
+
+	// The recipient is capped at the gate while the sender pays in full
+	s.GateLevel = 3
+	if err := s.TransferXP(from, to, 700); err != nil {
+		t.Fatalf("TransferXP() = %v", err)
+	}
+
+	checkXP(t, s, from, 300, 1)
+	checkXP(t, s, to, 599, 3)
+}
+
+func TestTransferXPOverflow(t *testing.T) {
+	s := testStore[any](t)
+	from := newPlayer(t, s, 10)
+	to := newPlayer(t, s, math.MaxInt64)
+
+	if err := s.TransferXP(from, to, 1); !errors.Is(err, ErrInvalidData) {
+		t.Errorf("TransferXP() past the maximum = %v, want ErrInvalidData", err)
+	}
+
+	checkXP(t, s, from, 10, 1)
+	checkXP(t, s, to, math.MaxInt64, 1)
+}
@@ -0,0 +1,63 @@
+package ghostplay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// GetUserStateByIDTx is GetUserStateByID run on q, typically an *sql.Tx, so
+// the caller sees writes made earlier in the same uncommitted transaction.
+func GetUserStateByIDTx[T any](q Querier, dbTableName string, id uuid.UUID) (*PlayerState[T], error) {
+	return NewStore[T](nil, dbTableName).GetUserStateByIDTx(q, id)
+}
+
+// GetUserStateByPhraseTx is GetUserStateByPhrase run on q, typically an
+// *sql.Tx, so the caller sees writes made earlier in the same uncommitted
+// transaction.
+func GetUserStateByPhraseTx[T any](q Querier, dbTableName, phrase string) (*PlayerState[T], error) {
+	return NewStore[T](nil, dbTableName).GetUserStateByPhraseTx(q, phrase)
+}
+
+// GetUserStateByIDTx is GetUserStateByID run on q, typically an *sql.Tx, so
+// the caller sees writes made earlier in the same uncommitted transaction.
+// The Store's own connections are not used.
+func (s *Store[T]) GetUserStateByIDTx(q Querier, id uuid.UUID) (*PlayerState[T], error) {
+	if q == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if id == uuid.Nil {
+		return nil, fmt.Errorf("%w: player ID cannot be nil", ErrInvalidData)
+	}
+
+	var state *PlayerState[T]
+	err := s.do("get_by_id", func(ctx context.Context) error {
+		var err error
+		state, err = s.getUserStateByID(ctx, q, id)
+		return err
+	})
+	return state, err
+}
+
+// GetUserStateByPhraseTx is GetUserStateByPhrase run on q, typically an
+// *sql.Tx, so the caller sees writes made earlier in the same uncommitted
+// transaction. The Store's own connections are not used.
+func (s *Store[T]) GetUserStateByPhraseTx(q Querier, phrase string) (*PlayerState[T], error) {
+	if q == nil {
+		return nil, fmt.Errorf("%w: nil database connection", ErrDatabaseConnection)
+	}
+
+	if phrase == "" {
+		return nil, fmt.Errorf("%w: phrase cannot be empty", ErrInvalidData)
+	}
+
+	var state *PlayerState[T]
+	err := s.do("get_by_phrase", func(ctx context.Context) error {
+		var err error
+		state, err = s.getUserStateByPhrase(ctx, q, phrase)
+		return err
+	})
+	return state, err
+}
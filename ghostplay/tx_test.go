@@ -0,0 +1,75 @@
+package ghostplay
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestReadsInsideTransaction(t *testing.T) {
+	s := testStore[any](t)
+	ctx := context.Background()
+
+	id := uuid.New()
+	if err := s.InitPlayer(id, "player", "phrase"); err != nil {
+		t.Fatalf("InitPlayer() = %v", err)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("BeginTx() = %v", err)
+	}
+	defer tx.Rollback()
+
+	p, err := s.GetUserStateByIDTx(tx, id)
+	if err != nil {
+		t.Fatalf("GetUserStateByIDTx() = %v", err)
+	}
+
+	if _, err := s.SaveContext(ctx, tx, p, 50); err != nil {
+		t.Fatalf("SaveContext() = %v", err)
+	}
+
+	// The uncommitted write is seen inside the transaction only
+	inside, err := s.GetUserStateByIDTx(tx, id)
+	if err != nil {
+		t.Fatalf("GetUserStateByIDTx() = %v", err)
+	}
+
+	if inside.XP != 50 {
+		t.Errorf("XP inside the transaction = %d, want 50", inside.XP)
+	}
+
+	byPhrase, err := s.GetUserStateByPhraseTx(tx, "phrase")
+	if err != nil {
+		t.Fatalf("GetUserStateByPhraseTx() = %v", err)
+	}
+
+	if byPhrase.XP != 50 {
+		t.Errorf("XP by phrase inside the transaction = %d, want 50", byPhrase.XP)
+	}
+
+	// Other connections don't see the write before it commits
+	xp, _, err := s.GetXPAndLevel(id)
+	if err != nil {
+		t.Fatalf("GetXPAndLevel() = %v", err)
+	}
+
+	if xp != 0 {
+		t.Errorf("XP outside the transaction = %d, want 0", xp)
+	}
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatalf("Rollback() = %v", err)
+	}
+
+	xp, _, err = s.GetXPAndLevel(id)
+	if err != nil {
+		t.Fatalf("GetXPAndLevel() = %v", err)
+	}
+
+	if xp != 0 {
+		t.Errorf("XP after rollback = %d, want 0", xp)
+	}
+}
@@ -2,4 +2,7 @@ module github.com/jrswab/ghostplay
 
 go 1.21.6
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/google/uuid v1.6.0
+	github.com/lib/pq v1.10.9
+)